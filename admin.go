@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// addCommand is the name of the admin command that adds a command.
+	addCommand = "addcmd"
+	// deleteCommand is the name of the admin command that deletes a command.
+	deleteCommand = "delcmd"
+)
+
+// isAdmin reports whether the user may manage commands.
+func isAdmin(config *Config, userID string) bool {
+	for _, id := range config.Admins {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAdmin responds to the admin command in content, which has had the
+// prefix stripped, and reports whether content was an admin command.
+func handleAdmin(s Messenger, m *discordgo.MessageCreate, config *Config, content string) bool {
+	word, args := splitWord(content)
+
+	var response string
+	switch {
+	case isAdminCommand(config, word, addCommand):
+		name, output := splitWord(args)
+		response = addCmd(config, name, output)
+	case isAdminCommand(config, word, deleteCommand):
+		name, _ := splitWord(args)
+		response = deleteCmd(name)
+	default:
+		return false
+	}
+
+	err := sendResponse(s, m, config, &discordgo.MessageSend{Content: response})
+	if err != nil {
+		slog.Error("error sending response", "command", word, "err", err)
+	}
+	return true
+}
+
+// isAdminCommand reports whether word is the given admin command.
+func isAdminCommand(config *Config, word, command string) bool {
+	if config.CaseInsensitive {
+		return strings.EqualFold(word, command)
+	}
+	return word == command
+}
+
+// isReserved reports whether name is used by a built-in or admin command.
+func isReserved(config *Config, name string) bool {
+	if config.CaseInsensitive {
+		name = strings.ToLower(name)
+	}
+	return isBuiltin(config, name, config.HelpCommand) ||
+		isBuiltin(config, name, config.StatsCommand) ||
+		isBuiltin(config, name, config.StatusCommand) ||
+		isBuiltin(config, name, addCommand) ||
+		isBuiltin(config, name, deleteCommand)
+}
+
+// addCmd adds or replaces a command, persisting it if storage is enabled,
+// and returns a message describing the result.
+func addCmd(config *Config, name, output string) string {
+	if name == "" || strings.TrimSpace(output) == "" {
+		return fmt.Sprintf("Usage: `%s%s <name> <output>`", config.Prefix.primary(), addCommand)
+	}
+	if isReserved(config, name) {
+		return fmt.Sprintf("`%s` is a built-in command and can't be replaced.", name)
+	}
+
+	// Check the output is a valid template before saving it.
+	_, err := newResponse(name, Response{Text: output})
+	if err != nil {
+		return fmt.Sprintf("Invalid output: %v", err)
+	}
+
+	// Replace an existing command that matches the name, rather than adding
+	// one that conflicts with it.
+	if key, exists := commandKey(config, name); exists {
+		name = key
+	}
+
+	if DB != nil {
+		err = saveCommand(DB, name, output)
+		if err != nil {
+			slog.Error("error saving command", "command", name, "err", err)
+			return "Error saving command."
+		}
+	}
+
+	var existed bool
+	updateCommands(func(commands map[string]CommandConfig) {
+		_, existed = commands[name]
+		commands[name] = CommandConfig{Output: Responses{{Text: output}}}
+	})
+
+	slog.Info("command added", "command", name)
+	if existed {
+		return fmt.Sprintf("Updated command `%s`.", name)
+	}
+	return fmt.Sprintf("Added command `%s`.", name)
+}
+
+// deleteCmd deletes a command, removing it from storage if enabled, and
+// returns a message describing the result.
+func deleteCmd(name string) string {
+	config := CurrentConfig.Load()
+	if name == "" {
+		return fmt.Sprintf("Usage: `%s%s <name>`", config.Prefix.primary(), deleteCommand)
+	}
+	key, exists := commandKey(config, name)
+	if !exists {
+		return fmt.Sprintf("There is no command `%s`.", name)
+	}
+	name = key
+
+	if DB != nil {
+		err := removeCommand(DB, name)
+		if err != nil {
+			slog.Error("error deleting command", "command", name, "err", err)
+			return "Error deleting command."
+		}
+	}
+
+	updateCommands(func(commands map[string]CommandConfig) {
+		delete(commands, name)
+	})
+
+	slog.Info("command deleted", "command", name)
+	return fmt.Sprintf("Deleted command `%s`.", name)
+}
+
+// commandKey returns the key in config.Commands of the command with the
+// given name, ignoring case if CaseInsensitive is set, and reports whether
+// there is one.
+func commandKey(config *Config, name string) (string, bool) {
+	if _, exists := config.Commands[name]; exists {
+		return name, true
+	}
+	if config.CaseInsensitive {
+		for key := range config.Commands {
+			if strings.EqualFold(key, name) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// updateCommands swaps in a copy of the current config with its commands
+// changed by update. Unless storage is enabled, changes are lost when the
+// config is reloaded.
+func updateCommands(update func(commands map[string]CommandConfig)) {
+	ConfigMu.Lock()
+	defer ConfigMu.Unlock()
+
+	config := *CurrentConfig.Load()
+	commands := make(map[string]CommandConfig, len(config.Commands)+1)
+	for k, v := range config.Commands {
+		commands[k] = v
+	}
+	update(commands)
+
+	config.Commands = commands
+	buildCommands(&config)
+	CurrentConfig.Store(&config)
+}
+
+// splitWord splits s into its first word and the rest, with surrounding
+// whitespace trimmed.
+func splitWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeleteCmd(t *testing.T) {
+	useConfig(t, `prefix: "!"
+case_insensitive: true
+commands:
+  Menu:
+    output: Pick one
+    components:
+      - label: Hi
+        custom_id: menu-hi
+        response: Hello
+`)
+	DB = nil
+
+	if got, want := deleteCmd("MENU"), "Deleted command `Menu`."; got != want {
+		t.Fatalf("deleteCmd = %q, want %q", got, want)
+	}
+	config := CurrentConfig.Load()
+	if _, exists := config.Commands["Menu"]; exists {
+		t.Error("command is still in the config")
+	}
+	if _, exists := config.lookup["menu"]; exists {
+		t.Error("command is still in the lookup")
+	}
+	if _, exists := config.buttons["menu-hi"]; exists {
+		t.Error("command's button is still handled")
+	}
+}
+
+func TestAddCmdReplacesMatchingCommand(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\ncase_insensitive: true\ncommands:\n  Ping: pong\n")
+	DB = nil
+
+	if got, want := addCmd(config, "PING", "pong!"), "Updated command `Ping`."; got != want {
+		t.Fatalf("addCmd = %q, want %q", got, want)
+	}
+	config = CurrentConfig.Load()
+	if len(config.Commands) != 1 || config.Commands["Ping"].Output[0].Text != "pong!" {
+		t.Errorf("commands = %v, want Ping replaced", config.Commands)
+	}
+}
+
+func TestAdminCommands(t *testing.T) {
+	DB = nil
+	const config = "prefix: \"!\"\nadmins: [\"30\"]\nhelp_command: help\ncommands:\n  ping: pong\n"
+	sent := handleMessages(t, config,
+		testMessage("30", "!addcmd hi Hello", false),
+		testMessage("31", "!hi", false),
+		testMessage("30", "!addcmd hi Hey {{.User}}", false),
+		testMessage("31", "!hi", false),
+		testMessage("30", "!delcmd nope", false),
+		testMessage("30", "!addcmd help Nope", false),
+		testMessage("31", "!addcmd bye Bye", false),
+		testMessage("32", "!bye", false),
+		testMessage("30", "!delcmd hi", false),
+		testMessage("32", "!hi", false),
+	)
+	want := []string{
+		"Added command `hi`.",
+		"Hello",
+		"Updated command `hi`.",
+		"Hey user-31",
+		"There is no command `nope`.",
+		"`help` is a built-in command and can't be replaced.",
+		"Deleted command `hi`.",
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
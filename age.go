@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// day is the unit of the minimum account age and membership duration.
+const day = 24 * time.Hour
+
+// accountCreated returns when the account with the given ID was created,
+// which is encoded in the ID.
+func accountCreated(userID string) (time.Time, error) {
+	return discordgo.SnowflakeTimestamp(userID)
+}
+
+// oldEnough reports whether since is at least days days before now.
+func oldEnough(since time.Time, days int, now time.Time) bool {
+	return days <= 0 || now.Sub(since) >= time.Duration(days)*day
+}
+
+// meetsAgeRequirements reports whether the author's account and guild
+// membership are old enough to use commands. Membership is only checked in
+// guilds, and the author's member info is fetched if member is nil and it is
+// needed.
+func meetsAgeRequirements(s Messenger, config *Config, guildID string, author *discordgo.User, member *discordgo.Member, now time.Time) bool {
+	if config.MinAccountAgeDays > 0 {
+		created, err := accountCreated(author.ID)
+		if err != nil {
+			slog.Error("error decoding account creation time", "author_id", author.ID, "err", err)
+			return false
+		}
+		if !oldEnough(created, config.MinAccountAgeDays, now) {
+			slog.Debug("ignoring command from new account", "author_id", author.ID)
+			return false
+		}
+	}
+
+	if config.MinMembershipDays <= 0 || guildID == "" {
+		return true
+	}
+
+	// Fetch the author's member info if it wasn't included in the event.
+	if member == nil {
+		var err error
+		member, err = s.GuildMember(guildID, author.ID)
+		if err != nil {
+			slog.Error("error fetching guild member", "err", err)
+			return false
+		}
+	}
+	if !oldEnough(member.JoinedAt, config.MinMembershipDays, now) {
+		slog.Debug("ignoring command from new member", "author_id", author.ID, "guild_id", guildID)
+		return false
+	}
+	return true
+}
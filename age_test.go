@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// snowflakeAt returns an ID created at t.
+func snowflakeAt(t time.Time) string {
+	const discordEpoch = 1420070400000
+	return strconv.FormatInt((t.UnixMilli()-discordEpoch)<<22, 10)
+}
+
+func TestAccountCreated(t *testing.T) {
+	// The example from Discord's documentation of snowflakes.
+	got, err := accountCreated("175928847299117063")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2016, 4, 30, 11, 18, 25, 796e6, time.UTC); !got.Equal(want) {
+		t.Errorf("accountCreated = %v, want %v", got, want)
+	}
+	if _, err := accountCreated("not an ID"); err == nil {
+		t.Error("accountCreated of an invalid ID succeeded")
+	}
+}
+
+func TestOldEnough(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		since time.Time
+		days  int
+		want  bool
+	}{
+		{since: now, days: 0, want: true},
+		{since: now.Add(-7 * day), days: 7, want: true},
+		{since: now.Add(-7*day + time.Second), days: 7, want: false},
+		{since: now.Add(-30 * day), days: 7, want: true},
+		{since: now.Add(time.Hour), days: 1, want: false},
+	}
+	for _, tt := range tests {
+		if got := oldEnough(tt.since, tt.days, now); got != tt.want {
+			t.Errorf("oldEnough(%v, %d) = %t, want %t", tt.since, tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestMeetsAgeRequirements(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	oldUser := &discordgo.User{ID: snowflakeAt(now.Add(-365 * day))}
+	newUser := &discordgo.User{ID: snowflakeAt(now.Add(-day))}
+	oldMember := &discordgo.Member{JoinedAt: now.Add(-30 * day)}
+	newMember := &discordgo.Member{JoinedAt: now.Add(-time.Hour)}
+	config := &Config{MinAccountAgeDays: 7, MinMembershipDays: 3}
+	tests := []struct {
+		name    string
+		config  *Config
+		guildID string
+		author  *discordgo.User
+		member  *discordgo.Member
+		want    bool
+	}{
+		{name: "old account and membership", config: config, guildID: "10", author: oldUser, member: oldMember, want: true},
+		{name: "new account", config: config, guildID: "10", author: newUser, member: oldMember},
+		{name: "new member", config: config, guildID: "10", author: oldUser, member: newMember},
+		{name: "new member in a DM", config: config, author: oldUser, want: true},
+		{name: "no requirements", config: &Config{}, guildID: "10", author: newUser, member: newMember, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := meetsAgeRequirements(&fakeMessenger{}, tt.config, tt.guildID, tt.author, tt.member, now); got != tt.want {
+				t.Errorf("meetsAgeRequirements = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxUploadSize is the largest file Discord accepts without boosts.
+const maxUploadSize = 10 << 20
+
+// checkAttachment logs a warning if the file at path is missing or too large
+// to upload.
+func checkAttachment(command, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		slog.Warn("command file can't be read", "command", command, "file", path, "err", err)
+		return
+	}
+	if info.Size() > maxUploadSize {
+		slog.Warn("command file is too large to upload", "command", command, "file", path, "size", info.Size(), "max", maxUploadSize)
+	}
+}
+
+// readAttachment reads the file at path into a file ready to upload.
+func readAttachment(path string) (*discordgo.File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxUploadSize {
+		return nil, fmt.Errorf("file %q is too large to upload", path)
+	}
+
+	name := filepath.Base(path)
+	return &discordgo.File{
+		Name:        name,
+		ContentType: mime.TypeByExtension(filepath.Ext(name)),
+		Reader:      bytes.NewReader(data),
+	}, nil
+}
+
+// rewindFiles resets the files so they can be sent again.
+func rewindFiles(files []*discordgo.File) {
+	for _, file := range files {
+		if r, ok := file.Reader.(*bytes.Reader); ok {
+			// Seeking to the start of a bytes.Reader can't fail.
+			_, _ = r.Seek(0, io.SeekStart)
+		}
+	}
+}
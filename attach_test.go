@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckAttachment(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.png")
+	if err := os.WriteFile(small, []byte("png"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	large := filepath.Join(dir, "large.png")
+	if err := os.WriteFile(large, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(large, maxUploadSize+1); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "exists", path: small},
+		{name: "missing", path: filepath.Join(dir, "missing.png"), want: "can't be read"},
+		{name: "too large", path: large, want: "too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t, "text", slog.LevelWarn)
+			checkAttachment("meme", tt.path)
+			if tt.want == "" {
+				if logs.Len() > 0 {
+					t.Errorf("unexpected warning: %s", logs)
+				}
+				return
+			}
+			if !strings.Contains(logs.String(), tt.want) {
+				t.Errorf("logs = %q, want a warning containing %q", logs, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondWithFileAndCaption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(path, []byte("png data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := newCommand("chart", CommandConfig{Output: Responses{{Text: "Here you go, {{.User}}"}}, File: path}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := cmd.respond(TemplateData{User: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 {
+		t.Fatalf("got %d messages, want 1", len(vals))
+	}
+	message := vals[0]
+	if want := "Here you go, user"; message.Content != want {
+		t.Errorf("caption = %q, want %q", message.Content, want)
+	}
+	if len(message.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(message.Files))
+	}
+	file := message.Files[0]
+	if file.Name != "chart.png" || file.ContentType != "image/png" {
+		t.Errorf("file = %q (%s), want chart.png (image/png)", file.Name, file.ContentType)
+	}
+	data, err := io.ReadAll(file.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "png data" {
+		t.Errorf("file data = %q, want %q", data, "png data")
+	}
+}
+
+func TestRespondWithFileOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meme.gif")
+	if err := os.WriteFile(path, []byte("gif"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := newCommand("meme", CommandConfig{File: path}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err := cmd.respond(TemplateData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Content != "" || len(vals[0].Files) != 1 {
+		t.Errorf("respond = %+v, want one message with only the file", vals)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/time/rate"
+)
+
+// AuditLimiter limits the rate of audit messages, separately from responses
+// so that auditing can't delay them.
+var AuditLimiter = rate.NewLimiter(rate.Every(time.Second), 5)
+
+// auditText returns the audit message for a use of the command.
+func auditText(user *discordgo.User, command, channelID string) string {
+	return fmt.Sprintf("%s (%s) ran `%s` in <#%s>", user.Username, user.ID, command, channelID)
+}
+
+// audit posts a use of the command to the audit channel, if one is set.
+// Commands used in the audit channel itself are not audited, so the audit
+// log can't feed itself.
+func audit(s Messenger, config *Config, user *discordgo.User, command, channelID string) {
+	if config.AuditChannel == "" || channelID == config.AuditChannel {
+		return
+	}
+
+	// Drop audit messages rather than let them queue up.
+	if !AuditLimiter.Allow() {
+		slog.Warn("audit message dropped by rate limiter", "command", command, "author_id", user.ID)
+		return
+	}
+
+	_, err := sendMessage(s, config, config.AuditChannel, &discordgo.MessageSend{
+		Content: auditText(user, command, channelID),
+		// Don't ping anyone from the audit log.
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		slog.Error("error sending audit message", "command", command, "err", err)
+	}
+}
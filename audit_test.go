@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestAudit(t *testing.T) {
+	user := &discordgo.User{ID: "30", Username: "user"}
+	tests := []struct {
+		name      string
+		config    *Config
+		channelID string
+		want      []sentMessage
+	}{
+		{
+			name:      "audited",
+			config:    &Config{AuditChannel: "40"},
+			channelID: "20",
+			want:      []sentMessage{{ChannelID: "40", Content: "user (30) ran `ping` in <#20>"}},
+		},
+		{name: "in the audit channel", config: &Config{AuditChannel: "40"}, channelID: "40"},
+		{name: "no audit channel", config: &Config{}, channelID: "20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			audit(fake, tt.config, user, "ping", tt.channelID)
+			sent := fake.messages()
+			if len(sent) != len(tt.want) {
+				t.Fatalf("sent %+v, want %+v", sent, tt.want)
+			}
+			for i := range sent {
+				if sent[i] != tt.want[i] {
+					t.Errorf("sent %+v, want %+v", sent[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// bannedWordsPattern returns a pattern that matches any of words as a whole
+// word, ignoring case, or nil if there are no words.
+func bannedWordsPattern(words []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	// Words are bounded by anything but letters, digits, and underscores,
+	// rather than by \b, so that words starting or ending with punctuation,
+	// such as "c++", still match.
+	return regexp.MustCompile(`(?i)(?:^|[^\pL\pN_])(?:` + strings.Join(quoted, "|") + `)(?:$|[^\pL\pN_])`)
+}
+
+// containsBannedWord reports whether content contains a banned word.
+func containsBannedWord(config *Config, content string) bool {
+	return config.bannedWords != nil && config.bannedWords.MatchString(content)
+}
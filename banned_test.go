@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContainsBannedWord(t *testing.T) {
+	config := &Config{bannedWords: bannedWordsPattern([]string{"ass", " darn ", "", "c++", "ber"})}
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{content: "what an ass", want: true},
+		{content: "ASS!", want: true},
+		{content: "Darn it", want: true},
+		{content: "a classic assessment", want: false},
+		{content: "darnation", want: false},
+		{content: "I like c++ a lot", want: true},
+		{content: "über", want: false},
+		{content: "!ping", want: false},
+	}
+	for _, tt := range tests {
+		if got := containsBannedWord(config, tt.content); got != tt.want {
+			t.Errorf("containsBannedWord(%q) = %t, want %t", tt.content, got, tt.want)
+		}
+	}
+
+	if bannedWordsPattern([]string{" ", ""}) != nil {
+		t.Error("pattern built from blank words")
+	}
+	if containsBannedWord(&Config{}, "anything") {
+		t.Error("banned word found with none configured")
+	}
+}
+
+func TestHandleBannedWords(t *testing.T) {
+	sent := handleMessages(t, "prefix: \"!\"\nbanned_words: [heck]\ncommands:\n  ping: pong\n  ping heck: pong\n",
+		testMessage("30", "!ping heck", false),
+		testMessage("30", "!ping", false),
+	)
+	if want := []string{"pong"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
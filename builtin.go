@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleBuiltin responds to the built-in command with the given name, if
+// there is one, and reports whether there was. Built-in commands never
+// override configured commands, so it should only be called if no configured
+// command matched.
+func handleBuiltin(s Messenger, m *discordgo.MessageCreate, config *Config, prefix, name string) bool {
+	var response string
+	switch {
+	case isBuiltin(config, name, config.HelpCommand):
+		response = helpText(config, prefix)
+	case isBuiltin(config, name, config.StatsCommand):
+		response = statsText(config, prefix)
+	case isBuiltin(config, name, config.StatusCommand):
+		response = statusText(Version, time.Since(StartTime), config.commandCount(), Reloads.Load())
+	default:
+		return false
+	}
+
+	// If the author is on cooldown, tell them so if enabled.
+	if remaining, ok := checkCooldown(config, m.Author.ID, memberRoles(m.Member), name); !ok {
+		sendCooldownNotice(s, m, config, name, remaining, messageData(m))
+		return true
+	}
+
+	err := sendResponse(s, m, config, &discordgo.MessageSend{Content: response})
+	if err != nil {
+		slog.Error("error sending response", "command", name, "err", err)
+		return true
+	}
+	slog.Debug("command handled", "command", name, "author_id", m.Author.ID, "channel_id", m.ChannelID)
+	audit(s, config, m.Author, name, m.ChannelID)
+	emitEvent(config, m.Author, name, m.GuildID, m.ChannelID)
+	return true
+}
+
+// isBuiltin reports whether the normalized command name matches the
+// configured name of a built-in command. An empty configured name disables
+// the built-in command.
+func isBuiltin(config *Config, name, builtin string) bool {
+	if builtin == "" {
+		return false
+	}
+	if config.CaseInsensitive {
+		builtin = strings.ToLower(builtin)
+	}
+	return name == builtin
+}
+
+// helpText returns a sorted list of all configured commands, used with the
+// given prefix.
+func helpText(config *Config, prefix string) string {
+	names := make(map[string]bool, len(config.lookup))
+	for _, cmd := range config.lookup {
+		names[cmd.Name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var b strings.Builder
+	b.WriteString("Available commands:")
+	for _, name := range sorted {
+		b.WriteString("\n- `" + prefix + name + "`")
+	}
+	return b.String()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHelpText(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\nhelp_command: help\ncommands:\n  ping:\n    output: pong\n    aliases: [p]\n  hi: hello\n")
+	want := "Available commands:\n- `!hi`\n- `!ping`"
+	if got := helpText(config, "!"); got != want {
+		t.Errorf("helpText = %q, want %q", got, want)
+	}
+}
+
+func TestDisabledCommand(t *testing.T) {
+	const config = "prefix: \"!\"\nhelp_command: help\ncommands:\n  ping: pong\n  noisy:\n    output: \"*honk*\"\n    enabled: %s\n"
+	tests := []struct {
+		enabled string
+		want    []string
+	}{
+		{enabled: "false", want: []string{"Available commands:\n- `!ping`"}},
+		{enabled: "true", want: []string{"*honk*", "Available commands:\n- `!noisy`\n- `!ping`"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enabled, func(t *testing.T) {
+			sent := handleMessages(t, fmt.Sprintf(config, tt.enabled),
+				testMessage("30", "!noisy", false),
+				testMessage("30", "!help", false),
+			)
+			if !reflect.DeepEqual(sent, tt.want) {
+				t.Errorf("sent %q, want %q", sent, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxButtonsPerRow is the most buttons Discord allows in an action row.
+	maxButtonsPerRow = 5
+	// maxButtons is the most buttons Discord allows on a message, in at most
+	// five action rows.
+	maxButtons = 5 * maxButtonsPerRow
+	// maxButtonLabelLength is the longest button label Discord allows.
+	maxButtonLabelLength = 80
+	// maxCustomIDLength is the longest button custom ID Discord allows.
+	maxCustomIDLength = 100
+)
+
+// buttonStyles maps the names used in the config to button styles.
+var buttonStyles = map[string]discordgo.ButtonStyle{
+	"primary":   discordgo.PrimaryButton,
+	"secondary": discordgo.SecondaryButton,
+	"success":   discordgo.SuccessButton,
+	"danger":    discordgo.DangerButton,
+	"link":      discordgo.LinkButton,
+}
+
+// Button is a button sent with a command's responses. Link buttons open URL.
+// Other buttons have a CustomID, and when clicked, toggle Role for the user
+// who clicked them and reply to them privately with Response, if set.
+type Button struct {
+	Label string `yaml:"label"`
+	// Style is "primary", the default, "secondary", "success", "danger",
+	// or "link". It defaults to "link" if URL is set.
+	Style    string `yaml:"style"`
+	URL      string `yaml:"url"`
+	CustomID string `yaml:"custom_id"`
+	Response string `yaml:"response"`
+	Role     string `yaml:"role"`
+}
+
+// style returns the button's style.
+func (b Button) style() discordgo.ButtonStyle {
+	if b.Style == "" {
+		if b.URL != "" {
+			return discordgo.LinkButton
+		}
+		return discordgo.PrimaryButton
+	}
+	return buttonStyles[b.Style]
+}
+
+// actionRows returns the buttons in as many action rows as needed.
+func actionRows(buttons []Button) []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	for start := 0; start < len(buttons); start += maxButtonsPerRow {
+		end := start + maxButtonsPerRow
+		if end > len(buttons) {
+			end = len(buttons)
+		}
+
+		var row discordgo.ActionsRow
+		for _, b := range buttons[start:end] {
+			button := discordgo.Button{Label: b.Label, Style: b.style()}
+			if button.Style == discordgo.LinkButton {
+				button.URL = b.URL
+			} else {
+				button.CustomID = b.CustomID
+			}
+			row.Components = append(row.Components, button)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// buildButtons returns the buttons of every enabled command that has a
+// custom ID, keyed by custom ID.
+func buildButtons(commands map[string]CommandConfig) map[string]Button {
+	buttons := make(map[string]Button)
+	for _, cmd := range commands {
+		if !cmd.enabled() {
+			continue
+		}
+		for _, b := range cmd.Components {
+			if b.style() != discordgo.LinkButton {
+				buttons[b.CustomID] = b
+			}
+		}
+	}
+	return buttons
+}
+
+// validateButtons returns all problems with the commands' buttons.
+func validateButtons(commands map[string]CommandConfig) []error {
+	var errs []error
+
+	// Sort names so errors are reported in the same order on every load.
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	owners := make(map[string]string)
+	for _, name := range names {
+		cmd := commands[name]
+		if len(cmd.Components) > maxButtons {
+			errs = append(errs, fmt.Errorf("command %q: %d components; at most %d are allowed", name, len(cmd.Components), maxButtons))
+		}
+		if len(cmd.Components) > 0 && cmd.Webhook != "" {
+			errs = append(errs, fmt.Errorf("command %q: components can't be sent through a webhook", name))
+		}
+
+		for i, b := range cmd.Components {
+			prefix := fmt.Sprintf("command %q: component %d", name, i+1)
+			if strings.TrimSpace(b.Label) == "" {
+				errs = append(errs, fmt.Errorf("%s: blank label", prefix))
+			} else if len([]rune(b.Label)) > maxButtonLabelLength {
+				errs = append(errs, fmt.Errorf("%s: label is longer than %d characters", prefix, maxButtonLabelLength))
+			}
+			if _, ok := buttonStyles[b.Style]; !ok && b.Style != "" {
+				errs = append(errs, fmt.Errorf("%s: unknown style %q", prefix, b.Style))
+				continue
+			}
+
+			// Link buttons only open their URL.
+			if b.style() == discordgo.LinkButton {
+				if u, err := url.Parse(b.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+					errs = append(errs, fmt.Errorf("%s: link buttons need an http or https URL", prefix))
+				}
+				if b.CustomID != "" || b.Response != "" || b.Role != "" {
+					errs = append(errs, fmt.Errorf("%s: link buttons can't have a custom_id, response, or role", prefix))
+				}
+				continue
+			}
+
+			if b.URL != "" {
+				errs = append(errs, fmt.Errorf("%s: only link buttons can have a URL", prefix))
+			}
+			switch {
+			case b.CustomID == "":
+				errs = append(errs, fmt.Errorf("%s: blank custom_id", prefix))
+			case len(b.CustomID) > maxCustomIDLength:
+				errs = append(errs, fmt.Errorf("%s: custom_id is longer than %d characters", prefix, maxCustomIDLength))
+			default:
+				if other, exists := owners[b.CustomID]; exists {
+					errs = append(errs, fmt.Errorf("%s: custom_id %q is also used by command %q", prefix, b.CustomID, other))
+				} else {
+					owners[b.CustomID] = name
+				}
+			}
+			if b.Response == "" && b.Role == "" {
+				errs = append(errs, fmt.Errorf("%s: no response or role", prefix))
+			}
+			if b.Role != "" && !isSnowflake(b.Role) {
+				errs = append(errs, fmt.Errorf("%s: role %q: must be a role ID", prefix, b.Role))
+			}
+		}
+	}
+	return errs
+}
+
+// buttonSession is the part of a Discord session used to respond to button
+// clicks. It is satisfied by *discordgo.Session.
+type buttonSession interface {
+	Messenger
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	GuildMemberRoleAdd(guildID, userID, roleID string, options ...discordgo.RequestOption) error
+	GuildMemberRoleRemove(guildID, userID, roleID string, options ...discordgo.RequestOption) error
+}
+
+// handleButton responds to a click of one of the configured buttons.
+func handleButton(s buttonSession, i *discordgo.InteractionCreate, config *Config) {
+	data := i.MessageComponentData()
+	b, ok := config.buttons[data.CustomID]
+	if !ok {
+		return
+	}
+
+	// The user is given in the member info in guilds, and directly in DMs.
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	// Ignore blacklisted and unapproved users, so that they can't change
+	// their roles.
+	if isBlacklisted(config, user.ID) {
+		slog.Debug("ignoring button click from blacklisted user", "author_id", user.ID)
+		return
+	}
+	if !isApproved(s, config, i.GuildID, user, i.Member) {
+		return
+	}
+
+	// Toggle the button's role, if any. Roles only exist in guilds.
+	text := b.Response
+	if b.Role != "" && i.Member != nil {
+		added, err := toggleRole(s, config, i.GuildID, i.Member, b.Role)
+		if err != nil {
+			slog.Error("error updating button role", "custom_id", data.CustomID, "user_id", user.ID, "role_id", b.Role, "err", err)
+			text = "Sorry, something went wrong."
+		} else {
+			slog.Info("button role updated", "custom_id", data.CustomID, "user_id", user.ID, "role_id", b.Role, "add", added)
+		}
+	}
+
+	// Interactions must be responded to; with no text, just acknowledge the
+	// click.
+	response := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}
+	if text != "" {
+		response = &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: text,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		}
+	}
+	if err := s.InteractionRespond(i.Interaction, response); err != nil {
+		slog.Error("error responding to button", "custom_id", data.CustomID, "err", err)
+	}
+}
+
+// toggleRole gives the member the role if they don't have it, and takes it
+// away if they do. It reports whether the role was given.
+func toggleRole(s buttonSession, config *Config, guildID string, member *discordgo.Member, roleID string) (bool, error) {
+	ctx, cancel := requestContext(config)
+	defer cancel()
+
+	if hasRole(member, []string{roleID}) {
+		return false, s.GuildMemberRoleRemove(guildID, member.User.ID, roleID, discordgo.WithContext(ctx))
+	}
+	return true, s.GuildMemberRoleAdd(guildID, member.User.ID, roleID, discordgo.WithContext(ctx))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeButtonSession records responses to button clicks and role changes.
+type fakeButtonSession struct {
+	*fakeMessenger
+	responses []*discordgo.InteractionResponse
+	added     []string
+	removed   []string
+}
+
+func (f *fakeButtonSession) InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error {
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+func (f *fakeButtonSession) GuildMemberRoleAdd(guildID, userID, roleID string, options ...discordgo.RequestOption) error {
+	f.added = append(f.added, roleID)
+	return nil
+}
+
+func (f *fakeButtonSession) GuildMemberRoleRemove(guildID, userID, roleID string, options ...discordgo.RequestOption) error {
+	f.removed = append(f.removed, roleID)
+	return nil
+}
+
+// buttonClick returns a click of the button with the custom ID by a member
+// with the given roles.
+func buttonClick(customID string, roles ...string) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "10",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "30"}, Roles: roles},
+		Data:    discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+	}}
+}
+
+func TestActionRows(t *testing.T) {
+	buttons := []Button{
+		{Label: "Docs", URL: "https://example.com"},
+		{Label: "Join", CustomID: "join", Role: "50"},
+		{Label: "Leave", Style: "danger", CustomID: "leave", Response: "Bye"},
+	}
+	for i := 0; i < 4; i++ {
+		buttons = append(buttons, Button{Label: "More", Style: "secondary", CustomID: "more"})
+	}
+
+	rows := actionRows(buttons)
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	first := rows[0].(discordgo.ActionsRow).Components
+	if len(first) != maxButtonsPerRow || len(rows[1].(discordgo.ActionsRow).Components) != 2 {
+		t.Errorf("rows have %d and %d buttons, want %d and 2", len(first), len(rows[1].(discordgo.ActionsRow).Components), maxButtonsPerRow)
+	}
+	want := []discordgo.MessageComponent{
+		discordgo.Button{Label: "Docs", Style: discordgo.LinkButton, URL: "https://example.com"},
+		discordgo.Button{Label: "Join", Style: discordgo.PrimaryButton, CustomID: "join"},
+		discordgo.Button{Label: "Leave", Style: discordgo.DangerButton, CustomID: "leave"},
+	}
+	if !reflect.DeepEqual(first[:3], want) {
+		t.Errorf("buttons = %+v, want %+v", first[:3], want)
+	}
+}
+
+func TestValidateButtons(t *testing.T) {
+	many := make([]Button, maxButtons+1)
+	for i := range many {
+		many[i] = Button{Label: "Docs", URL: "https://example.com"}
+	}
+	tests := []struct {
+		name    string
+		buttons []Button
+		wantErr string
+	}{
+		{name: "valid", buttons: []Button{{Label: "Docs", URL: "https://example.com"}, {Label: "Join", CustomID: "join", Role: "50"}}},
+		{name: "too many", buttons: many, wantErr: "at most 25"},
+		{name: "blank label", buttons: []Button{{URL: "https://example.com"}}, wantErr: "blank label"},
+		{name: "link without URL", buttons: []Button{{Label: "Docs", Style: "link"}}, wantErr: "need an http or https URL"},
+		{name: "no custom ID", buttons: []Button{{Label: "Join", Role: "50"}}, wantErr: "blank custom_id"},
+		{name: "no action", buttons: []Button{{Label: "Join", CustomID: "join"}}, wantErr: "no response or role"},
+		{name: "unknown style", buttons: []Button{{Label: "Join", Style: "shiny", CustomID: "join", Role: "50"}}, wantErr: "unknown style"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateButtons(map[string]CommandConfig{"menu": {Output: Responses{{Text: "Menu"}}, Components: tt.buttons}})
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Fatalf("validateButtons = %v, want no errors", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Fatalf("validateButtons = %v, want one error containing %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleButton(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\ncommands:\n  roles:\n    output: Pick a role\n    components:\n      - label: News\n        custom_id: news\n        role: \"50\"\n        response: Toggled news\n      - label: Hi\n        custom_id: hi\n        response: Hello!\n      - label: Quiet\n        custom_id: quiet\n        role: \"51\"\n")
+	tests := []struct {
+		name        string
+		click       *discordgo.InteractionCreate
+		wantAdded   []string
+		wantRemoved []string
+		wantType    discordgo.InteractionResponseType
+		wantContent string
+	}{
+		{name: "response", click: buttonClick("hi"), wantType: discordgo.InteractionResponseChannelMessageWithSource, wantContent: "Hello!"},
+		{name: "add role", click: buttonClick("news"), wantAdded: []string{"50"}, wantType: discordgo.InteractionResponseChannelMessageWithSource, wantContent: "Toggled news"},
+		{name: "remove role", click: buttonClick("news", "50"), wantRemoved: []string{"50"}, wantType: discordgo.InteractionResponseChannelMessageWithSource, wantContent: "Toggled news"},
+		{name: "role without response", click: buttonClick("quiet"), wantAdded: []string{"51"}, wantType: discordgo.InteractionResponseDeferredMessageUpdate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeButtonSession{fakeMessenger: &fakeMessenger{}}
+			handleButton(fake, tt.click, config)
+			if !reflect.DeepEqual(fake.added, tt.wantAdded) || !reflect.DeepEqual(fake.removed, tt.wantRemoved) {
+				t.Errorf("added %q and removed %q, want %q and %q", fake.added, fake.removed, tt.wantAdded, tt.wantRemoved)
+			}
+			if len(fake.responses) != 1 {
+				t.Fatalf("got %d responses, want 1", len(fake.responses))
+			}
+			resp := fake.responses[0]
+			if resp.Type != tt.wantType {
+				t.Errorf("response type = %d, want %d", resp.Type, tt.wantType)
+			}
+			if tt.wantContent != "" && (resp.Data == nil || resp.Data.Content != tt.wantContent || resp.Data.Flags != discordgo.MessageFlagsEphemeral) {
+				t.Errorf("response data = %+v, want ephemeral %q", resp.Data, tt.wantContent)
+			}
+		})
+	}
+
+	fake := &fakeButtonSession{fakeMessenger: &fakeMessenger{}}
+	handleButton(fake, buttonClick("unknown"), config)
+	if len(fake.responses) != 0 {
+		t.Errorf("responded to an unknown button: %+v", fake.responses)
+	}
+}
+
+func TestHandleButtonApproval(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\nwhitelist_enabled: true\nwhitelist: [\"30\", \"32\"]\nblacklist: [\"32\"]\ncommands:\n  roles:\n    output: Pick a role\n    components:\n      - label: News\n        custom_id: news\n        role: \"50\"\n        response: Toggled news\n")
+	tests := []struct {
+		name      string
+		userID    string
+		wantAdded []string
+	}{
+		{name: "approved", userID: "30", wantAdded: []string{"50"}},
+		{name: "unapproved", userID: "31"},
+		{name: "blacklisted", userID: "32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeButtonSession{fakeMessenger: &fakeMessenger{}}
+			click := buttonClick("news")
+			click.Member.User.ID = tt.userID
+			handleButton(fake, click, config)
+			if !reflect.DeepEqual(fake.added, tt.wantAdded) || len(fake.removed) != 0 {
+				t.Errorf("added %q and removed %q, want %q added", fake.added, fake.removed, tt.wantAdded)
+			}
+		})
+	}
+}
@@ -0,0 +1,717 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// matchExact matches messages that are exactly the command's name.
+	matchExact = "exact"
+	// matchContains matches messages that contain the command's name.
+	matchContains = "contains"
+	// matchRegex matches messages against the command's name as a regular
+	// expression.
+	matchRegex = "regex"
+)
+
+const (
+	// modeRandom sends one of a command's responses, chosen at random.
+	modeRandom = "random"
+	// modeSequence sends every one of a command's responses, in order.
+	modeSequence = "sequence"
+)
+
+// defaultSequenceDelay is the delay between responses of a sequence if none
+// is set.
+const defaultSequenceDelay = time.Second
+
+// minContainsLength is the shortest name a command matched by substring may
+// have, so that it isn't triggered by too many messages.
+const minContainsLength = 3
+
+// CommandConfig defines a command. In YAML, it may be given as either its
+// responses alone or a mapping with an "output" key holding the responses.
+type CommandConfig struct {
+	// Output holds the command's possible responses.
+	Output Responses `yaml:"output"`
+	// Channels is a slice of channel IDs the command may be used in. If empty,
+	// the command may be used in any channel.
+	Channels []string `yaml:"channels"`
+	// Aliases is a slice of other names the command may be used by.
+	Aliases []string `yaml:"aliases"`
+	// Users is a slice of IDs of users who may use the command. If Users and
+	// Roles are both empty, anyone approved by the whitelist may use it.
+	Users []string `yaml:"users"`
+	// Roles is a slice of IDs of roles whose members may use the command.
+	Roles []string `yaml:"roles"`
+	// Reaction is an emoji to react to the command with, given as either a
+	// unicode emoji or a custom emoji in the form "name:id".
+	Reaction string `yaml:"reaction"`
+	// CommandCooldown is the minimum interval in seconds between uses of the
+	// command within its cooldown scope. It overrides the global command
+	// cooldown, if set.
+	CommandCooldown int `yaml:"command_cooldown"`
+	// CooldownScope is who the command cooldown applies to: "user", the
+	// default, for each user, "channel" for each channel, or "global" for
+	// everyone.
+	CooldownScope string `yaml:"cooldown_scope"`
+	// File is the path of a file to attach to responses. The output, if
+	// any, is sent as its caption.
+	File string `yaml:"file"`
+	// Permission is the name of a permission, such as "manage_messages",
+	// that users must have in the channel to use the command. Commands that
+	// require a permission can't be used in DMs.
+	Permission string `yaml:"permission"`
+	// Ephemeral defines if slash command responses are only shown to the
+	// user who used the command. It does not affect messages.
+	Ephemeral bool `yaml:"ephemeral"`
+	// Args defines if the command may be followed by arguments, which are
+	// available to responses. Only the first word of the message is then
+	// matched against the command's name.
+	Args bool `yaml:"args"`
+	// Mode is how responses are chosen: "random", the default, sends one
+	// response chosen at random, and "sequence" sends every response in
+	// order.
+	Mode string `yaml:"mode"`
+	// SequenceDelay is the delay in seconds between responses if Mode is
+	// "sequence". It defaults to one second.
+	SequenceDelay int `yaml:"sequence_delay"`
+	// TargetChannel is the ID of a channel to send responses to, instead of
+	// the channel the command was used in. It does not affect slash commands.
+	TargetChannel string `yaml:"target_channel"`
+	// Acknowledge is a message sent in the channel the command was used in
+	// when responses are sent to TargetChannel, if set.
+	Acknowledge string `yaml:"acknowledge"`
+	// Thread defines if a thread is started on the command message and
+	// responses are sent there. Channels that don't support threads are
+	// responded to as usual. It does not affect slash commands.
+	Thread bool `yaml:"thread"`
+	// Components are buttons sent with the command's responses. They can't
+	// be sent through a webhook.
+	Components []Button `yaml:"components"`
+	// Webhook is the URL of a webhook to send responses through, instead of
+	// sending them as the bot. It overrides the global webhook, if any.
+	Webhook string `yaml:"webhook"`
+	// Match is how messages are matched to the command: "exact", the
+	// default, "contains", or "regex".
+	Match string `yaml:"match"`
+	// Enabled defines if the command may be used. If nil, it is true, so
+	// that commands can be turned off without removing them.
+	Enabled *bool `yaml:"enabled"`
+	// Confirm is a message asking the user to confirm the command by
+	// reacting, if set. Responses are only sent once they confirm it. It
+	// does not affect slash commands.
+	Confirm string `yaml:"confirm"`
+	// ConfirmTimeout is how long in seconds to wait for a confirmation. It
+	// defaults to 30 seconds.
+	ConfirmTimeout int `yaml:"confirm_timeout"`
+	// Log defines if uses of the command are logged. If true, they are
+	// logged at info level, and if false, never. If nil, they are logged at
+	// debug level.
+	Log *bool `yaml:"log"`
+	// Regex defines if the command's name is a regular expression matched
+	// against the whole message, rather than a name to match exactly. It is
+	// the same as setting Match to "regex".
+	Regex bool `yaml:"regex"`
+}
+
+// enabled reports whether the command may be used.
+func (c CommandConfig) enabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *CommandConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Accept the full mapping form. A separate type is used to avoid
+	// recursing into this method.
+	type plain CommandConfig
+	var full plain
+	if err := unmarshal(&full); err == nil {
+		*c = CommandConfig(full)
+		return nil
+	}
+
+	// Otherwise, expect responses alone.
+	var output Responses
+	if err := unmarshal(&output); err != nil {
+		return err
+	}
+	*c = CommandConfig{Output: output}
+	return nil
+}
+
+// Response is a possible output for a command. In YAML, it may be given as
+// either a string, for a plain text message, or a mapping, for an embed or a
+// weighted plain text message. Either mapping may give a weight.
+type Response struct {
+	Text  string
+	Embed *Embed
+	// Weight is how likely the response is to be chosen relative to the
+	// command's other responses. If nil, it is 1.
+	Weight *int
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (r *Response) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Accept a plain string.
+	var text string
+	if err := unmarshal(&text); err == nil {
+		*r = Response{Text: text}
+		return nil
+	}
+
+	// Otherwise, expect a mapping of either text or an embed.
+	var mapping struct {
+		Text   string `yaml:"text"`
+		Weight *int   `yaml:"weight"`
+		Embed  `yaml:",inline"`
+	}
+	if err := unmarshal(&mapping); err != nil {
+		return err
+	}
+	if mapping.Text != "" {
+		if mapping.Embed != (Embed{}) {
+			return errors.New("response has both text and embed fields")
+		}
+		*r = Response{Text: mapping.Text, Weight: mapping.Weight}
+		return nil
+	}
+	embed := mapping.Embed
+	*r = Response{Embed: &embed, Weight: mapping.Weight}
+	return nil
+}
+
+// Embed defines a rich embed response.
+type Embed struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Color       string `yaml:"color"`
+	URL         string `yaml:"url"`
+}
+
+// Responses is a list of possible outputs for a command. In YAML, it may be
+// given as either a single response or a list of responses.
+type Responses []Response
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (r *Responses) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Accept a single response.
+	var single Response
+	if err := unmarshal(&single); err == nil {
+		*r = Responses{single}
+		return nil
+	}
+
+	// Otherwise, expect a list of responses.
+	var list []Response
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*r = list
+	return nil
+}
+
+// TemplateData is the data available to response templates.
+type TemplateData struct {
+	// User is the author's username.
+	User string
+	// Mention is the string that mentions the author.
+	Mention string
+	// ChannelID is the ID of the channel the command was sent in.
+	ChannelID string
+	// GuildID is the ID of the guild the command was sent in, if any.
+	GuildID string
+	// Remaining is the number of seconds left on the author's cooldown. It
+	// is only set for cooldown messages.
+	Remaining int
+	// Command is the name of the command the author tried to use. It is only
+	// set for unknown command messages.
+	Command string
+	// Prefix is the prefix the author used. It is only set for unknown
+	// command messages.
+	Prefix string
+	// Locale is the locale responses are translated into, if any, such as
+	// "en-US".
+	Locale string
+	// Args holds the arguments given after the command's name, split on
+	// whitespace, if the command takes arguments.
+	Args []string
+	// ArgString holds the arguments given after the command's name as is.
+	ArgString string
+}
+
+// Command is a command ready to be responded to.
+type Command struct {
+	// Name is the command's name, as configured.
+	Name string
+	// Responses holds the parsed responses.
+	Responses []*response
+	// Channels is a slice of channel IDs the command may be used in.
+	Channels []string
+	// Users is a slice of IDs of users who may use the command.
+	Users []string
+	// Roles is a slice of IDs of roles whose members may use the command.
+	Roles []string
+	// Pattern is the regular expression that triggers the command, if the
+	// command is matched by pattern.
+	Pattern *regexp.Regexp
+	// Contains is the substring that triggers the command, if the command is
+	// matched by substring. It is lowercased if case is ignored.
+	Contains string
+	// Reaction is the emoji to react to the command with, in the form used
+	// by the Discord API.
+	Reaction string
+	// Webhook is the webhook to send responses through, if any.
+	Webhook *Webhook
+	// File is the path of a file to attach to responses, if any.
+	File string
+	// Cooldown is the minimum interval between uses of the command within
+	// its cooldown scope, if set.
+	Cooldown time.Duration
+	// CooldownScope is who Cooldown applies to.
+	CooldownScope string
+	// Permission is the permission users must have to use the command, if
+	// any.
+	Permission int64
+	// Ephemeral defines if slash command responses are only shown to the
+	// user who used the command.
+	Ephemeral bool
+	// Localized holds the parsed translated responses by lowercase locale.
+	Localized map[string][]*response
+	// Args defines if the command may be followed by arguments.
+	Args bool
+	// TargetChannel is the ID of the channel to send responses to, if not
+	// the channel the command was used in.
+	TargetChannel string
+	// Acknowledge is the parsed Acknowledge message, if set.
+	Acknowledge *template.Template
+	// Thread defines if responses are sent in a thread started on the
+	// command message.
+	Thread bool
+	// Components holds the action rows of buttons sent with the command's
+	// responses, if any.
+	Components []discordgo.MessageComponent
+	// Log defines if uses of the command are logged, if set.
+	Log *bool
+	// Confirm is the message asking users to confirm the command, if set.
+	Confirm string
+	// ConfirmTimeout is how long to wait for a confirmation, if set.
+	ConfirmTimeout time.Duration
+	// Sequence defines if every response is sent in order, rather than one
+	// chosen at random.
+	Sequence bool
+	// SequenceDelay is the delay between responses, if Sequence is set.
+	SequenceDelay time.Duration
+}
+
+// response is a parsed Response.
+type response struct {
+	weight int
+	text   *responseTemplate
+	// The following fields are only set for embeds.
+	embed       bool
+	title       *responseTemplate
+	description *responseTemplate
+	color       int
+	url         string
+}
+
+// newCommand parses the given command's responses as templates, and its name
+// as a regular expression if needed.
+func newCommand(name string, config CommandConfig, caseInsensitive bool) (*Command, error) {
+	if len(config.Output) == 0 && config.File == "" && config.Reaction == "" {
+		return nil, errors.New("no responses, file, or reaction")
+	}
+
+	cmd := &Command{
+		Name:           name,
+		Channels:       config.Channels,
+		Users:          config.Users,
+		Roles:          config.Roles,
+		File:           config.File,
+		Cooldown:       time.Duration(config.CommandCooldown) * time.Second,
+		CooldownScope:  config.CooldownScope,
+		Ephemeral:      config.Ephemeral,
+		Args:           config.Args,
+		TargetChannel:  config.TargetChannel,
+		Thread:         config.Thread,
+		Components:     actionRows(config.Components),
+		Log:            config.Log,
+		Confirm:        config.Confirm,
+		ConfirmTimeout: time.Duration(config.ConfirmTimeout) * time.Second,
+		Sequence:       config.Mode == modeSequence,
+		SequenceDelay:  time.Duration(config.SequenceDelay) * time.Second,
+	}
+	if cmd.Sequence && cmd.SequenceDelay <= 0 {
+		cmd.SequenceDelay = defaultSequenceDelay
+	}
+	if config.File != "" {
+		checkAttachment(name, config.File)
+	}
+	if config.Permission != "" {
+		cmd.Permission = resolvePermission(name, config.Permission)
+	}
+	if config.Acknowledge != "" {
+		acknowledge, err := template.New(name).Parse(config.Acknowledge)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Acknowledge = acknowledge
+	}
+	if config.Webhook != "" {
+		hook, err := parseWebhookURL(config.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Webhook = hook
+	}
+	if config.Reaction != "" {
+		reaction, err := parseEmoji(config.Reaction)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Reaction = reaction
+	}
+	match := config.Match
+	if config.Regex {
+		match = matchRegex
+	}
+	switch match {
+	case "", matchExact:
+	case matchContains:
+		if len([]rune(name)) < minContainsLength {
+			return nil, fmt.Errorf("name is too short to match by substring; must be at least %d characters", minContainsLength)
+		}
+		cmd.Contains = name
+		if caseInsensitive {
+			cmd.Contains = strings.ToLower(name)
+		}
+		// The bot ignores its own messages, but not necessarily those sent
+		// through a webhook.
+		for _, r := range config.Output {
+			if strings.Contains(r.Text, name) {
+				slog.Warn("command output contains its own name and may trigger itself", "command", name)
+				break
+			}
+		}
+	case matchRegex:
+		expr := name
+		if caseInsensitive {
+			expr = "(?i)" + expr
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Pattern = pattern
+	default:
+		return nil, fmt.Errorf("unknown match mode %q", config.Match)
+	}
+
+	for _, r := range config.Output {
+		parsed, err := newResponse(name, r)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Responses = append(cmd.Responses, parsed)
+	}
+
+	return cmd, nil
+}
+
+// newResponse parses the given response as templates.
+func newResponse(name string, r Response) (*response, error) {
+	var err error
+
+	weight := 1
+	if r.Weight != nil {
+		weight = *r.Weight
+	}
+	if weight <= 0 {
+		return nil, fmt.Errorf("response weight %d is not positive", weight)
+	}
+
+	// Parse a plain text response.
+	if r.Embed == nil {
+		parsed := &response{weight: weight}
+		parsed.text, err = parseResponseTemplate(name, checkEmojiResponse(name, r.Text))
+		return parsed, err
+	}
+
+	// Parse an embed response.
+	parsed := &response{weight: weight, embed: true, url: r.Embed.URL}
+	parsed.title, err = parseResponseTemplate(name, r.Embed.Title)
+	if err != nil {
+		return nil, err
+	}
+	parsed.description, err = parseResponseTemplate(name, r.Embed.Description)
+	if err != nil {
+		return nil, err
+	}
+	if r.Embed.Color != "" {
+		parsed.color, err = parseColor(r.Embed.Color)
+		if err != nil {
+			slog.Warn("invalid embed color; ignoring color", "command", name, "color", r.Embed.Color)
+		}
+	}
+
+	return parsed, nil
+}
+
+// parseEmoji returns the emoji in the form used by the Discord API. Custom
+// emoji may be given as "name:id", or as copied from Discord, "<:name:id>" or
+// "<a:name:id>". Anything else is assumed to be a unicode emoji.
+func parseEmoji(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("empty emoji")
+	}
+
+	// Strip the brackets and animated marker from a copied custom emoji.
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		emoji, ok := parseCustomEmoji(s)
+		if !ok {
+			return "", fmt.Errorf("invalid custom emoji %q", s)
+		}
+		return emoji.Name + ":" + emoji.ID, nil
+	}
+
+	name, id, isCustom := strings.Cut(s, ":")
+	if !isCustom {
+		return s, nil
+	}
+	if name == "" || id == "" {
+		return "", fmt.Errorf("invalid custom emoji %q", s)
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid custom emoji ID %q", id)
+	}
+	return name + ":" + id, nil
+}
+
+// parseColor parses a color given as a decimal integer or as a hex value
+// prefixed with "#" or "0x".
+func parseColor(s string) (int, error) {
+	var n int64
+	var err error
+	switch {
+	case strings.HasPrefix(s, "#"):
+		n, err = strconv.ParseInt(s[1:], 16, 32)
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		n, err = strconv.ParseInt(s[2:], 16, 32)
+	default:
+		n, err = strconv.ParseInt(s, 10, 32)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 0xFFFFFF {
+		return 0, errors.New("color out of range")
+	}
+	return int(n), nil
+}
+
+// allowedIn reports whether the command may be used in the given channel.
+func (c *Command) allowedIn(channelID string) bool {
+	if len(c.Channels) == 0 {
+		return true
+	}
+	for _, id := range c.Channels {
+		if id == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedFor reports whether the author may use the command, if it is
+// restricted to certain users or roles. Roles only apply in guilds, and the
+// author's member info is fetched if member is nil and it is needed.
+func (c *Command) allowedFor(s Messenger, guildID string, author *discordgo.User, member *discordgo.Member) bool {
+	if len(c.Users) == 0 && len(c.Roles) == 0 {
+		return true
+	}
+	for _, id := range c.Users {
+		if id == author.ID {
+			return true
+		}
+	}
+	if len(c.Roles) == 0 || guildID == "" {
+		return false
+	}
+
+	// Fetch the author's member info if it wasn't included in the event.
+	if member == nil {
+		var err error
+		member, err = s.GuildMember(guildID, author.ID)
+		if err != nil {
+			slog.Error("error fetching guild member", "err", err)
+			return false
+		}
+	}
+	return hasRole(member, c.Roles)
+}
+
+// permittedFor reports whether the user with the given ID may use the command
+// in the given channel. Commands that require a permission are never
+// permitted in DMs, where permissions don't apply.
+func (c *Command) permittedFor(s Messenger, guildID, userID, channelID string) bool {
+	if c.Permission == 0 {
+		return true
+	}
+	if guildID == "" {
+		return false
+	}
+	permissions, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		slog.Error("error getting permissions", "command", c.Name, "author_id", userID, "channel_id", channelID, "err", err)
+		return false
+	}
+	return permitted(permissions, c.Permission)
+}
+
+// logHandled logs a use of the command, at the level given by its Log
+// setting.
+func (c *Command) logHandled(userID, channelID string) {
+	level := slog.LevelDebug
+	if c.Log != nil {
+		if !*c.Log {
+			return
+		}
+		level = slog.LevelInfo
+	}
+	slog.Log(context.Background(), level, "command handled", "command", c.Name, "author_id", userID, "channel_id", channelID)
+}
+
+// channelFor returns the ID of the channel to respond to a command used in
+// the given channel in.
+func (c *Command) channelFor(channelID string) string {
+	if c.TargetChannel != "" {
+		return c.TargetChannel
+	}
+	return channelID
+}
+
+// commandCooldown returns the minimum interval between uses of the command
+// by anyone.
+func (c *Command) commandCooldown(config *Config) time.Duration {
+	if c.Cooldown > 0 {
+		return c.Cooldown
+	}
+	return config.commandCooldown()
+}
+
+// hasResponse reports whether the command sends a message when used.
+func (c *Command) hasResponse() bool {
+	return len(c.Responses) > 0 || c.File != ""
+}
+
+// respond renders the messages to send in response to the command with the
+// given data: a randomly chosen response, or every response in order if the
+// command is a sequence. The command's file, if any, is attached to the first
+// message.
+func (c *Command) respond(data TemplateData) ([]*discordgo.MessageSend, error) {
+	var messages []*discordgo.MessageSend
+	responses := c.responsesFor(data.Locale)
+	switch {
+	case len(responses) == 0:
+		messages = []*discordgo.MessageSend{{}}
+	case c.Sequence:
+		for _, r := range responses {
+			message, err := r.render(data)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+	default:
+		message, err := pickResponse(responses, rand.Intn).render(data)
+		if err != nil {
+			return nil, err
+		}
+		messages = []*discordgo.MessageSend{message}
+	}
+
+	if c.File != "" {
+		file, err := readAttachment(c.File)
+		if err != nil {
+			return nil, err
+		}
+		messages[0].Files = []*discordgo.File{file}
+	}
+	messages[0].Components = c.Components
+
+	return messages, nil
+}
+
+// render renders the response with the given data.
+func (r *response) render(data TemplateData) (*discordgo.MessageSend, error) {
+	// Render a plain text response.
+	if !r.embed {
+		text, err := r.text.render(data)
+		if err != nil {
+			return nil, err
+		}
+		return &discordgo.MessageSend{Content: text}, nil
+	}
+
+	// Render an embed response.
+	title, err := r.title.render(data)
+	if err != nil {
+		return nil, err
+	}
+	description, err := r.description.render(data)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       title,
+			Description: description,
+			Color:       r.color,
+			URL:         r.url,
+		}},
+	}, nil
+}
+
+// pickResponse chooses one of responses at random, weighted by their
+// weights, using intn to get a random integer in [0, n).
+func pickResponse(responses []*response, intn func(n int) int) *response {
+	if len(responses) == 1 {
+		return responses[0]
+	}
+
+	total := 0
+	for _, r := range responses {
+		total += r.weight
+	}
+	n := intn(total)
+	for _, r := range responses {
+		if n < r.weight {
+			return r
+		}
+		n -= r.weight
+	}
+	return responses[len(responses)-1]
+}
+
+// render executes tmpl with the given data.
+func render(tmpl *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewCommandTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", text: "pong", want: "pong"},
+		{name: "mention", text: "Hi {{.Mention}}!", want: "Hi <@30>!"},
+		{name: "user and channel", text: "{{.User}} in {{.ChannelID}}", want: "user in 20"},
+		{name: "malformed", text: "Hi {{.Mention", wantErr: true},
+		{name: "unknown field", text: "{{.Nope}}", wantErr: true},
+	}
+	data := TemplateData{User: "user", Mention: "<@30>", ChannelID: "20", GuildID: "10"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := newCommand("hi", CommandConfig{Output: Responses{{Text: tt.text}}}, false)
+			if err == nil {
+				var vals []*discordgo.MessageSend
+				vals, err = cmd.respond(data)
+				if err == nil && vals[0].Content != tt.want {
+					t.Errorf("response = %q, want %q", vals[0].Content, tt.want)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildLookupSkipsInvalidCommands(t *testing.T) {
+	config := &Config{Commands: map[string]CommandConfig{
+		"good": {Output: Responses{{Text: "Hi {{.User}}"}}},
+		"bad":  {Output: Responses{{Text: "Hi {{.User"}}},
+	}}
+	buildLookup(config)
+	if _, ok := config.lookup["good"]; !ok {
+		t.Error("valid command is missing from the lookup")
+	}
+	if _, ok := config.lookup["bad"]; ok {
+		t.Error("command with a malformed template is in the lookup")
+	}
+}
+
+func TestBuildLookupCaseConflict(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		logs := captureLogs(t, "text", slog.LevelWarn)
+		config := &Config{CaseInsensitive: true, Commands: map[string]CommandConfig{
+			"ping": {Output: Responses{{Text: "pong"}}},
+			"Ping": {Output: Responses{{Text: "Pong!"}}},
+		}}
+		buildLookup(config)
+
+		if cmd := config.lookup["ping"]; cmd == nil || cmd.Name != "Ping" {
+			t.Fatalf("lookup[ping] = %+v, want Ping, the first in sort order", cmd)
+		}
+		if len(config.lookup) != 1 {
+			t.Errorf("lookup has %d keys, want 1", len(config.lookup))
+		}
+		got := logs.String()
+		if n := strings.Count(got, "conflicts with another"); n != 1 {
+			t.Fatalf("logged %d conflicts, want 1: %q", n, got)
+		}
+		if !strings.Contains(got, "command=ping") || !strings.Contains(got, "conflict=Ping") {
+			t.Errorf("conflict warning %q doesn't name ping and Ping", got)
+		}
+	}
+}
+
+func TestBuildLookupSkipsInvalidPatterns(t *testing.T) {
+	logs := captureLogs(t, "text", slog.LevelWarn)
+	config := &Config{Commands: map[string]CommandConfig{
+		"(bad":                   {Output: Responses{{Text: "never"}}, Match: matchRegex},
+		"good (morning|evening)": {Output: Responses{{Text: "Hello!"}}, Match: matchRegex},
+		"ping":                   {Output: Responses{{Text: "pong"}}},
+		"p.ng":                   {Output: Responses{{Text: "pattern"}}, Match: matchRegex},
+	}}
+	buildLookup(config)
+
+	var names []string
+	for _, cmd := range config.patterns {
+		names = append(names, cmd.Name)
+	}
+	if want := []string{"good (morning|evening)", "p.ng"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("patterns = %q, want %q", names, want)
+	}
+	if !strings.Contains(logs.String(), "invalid command; skipping it") || !strings.Contains(logs.String(), "command=(bad") {
+		t.Errorf("logs %q don't warn about the invalid pattern", logs)
+	}
+	if cmd := config.lookup["ping"]; cmd == nil || cmd.Pattern != nil {
+		t.Errorf("lookup[ping] = %+v, want the exact command", cmd)
+	}
+}
+
+func TestHandleRegexCommand(t *testing.T) {
+	const config = "prefix: \"!\"\ncommands:\n  \"good (morning|evening)\":\n    output: Hello!\n    match: regex\n  \"p.ng\":\n    output: pattern\n    match: regex\n  ping: pong\n"
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{content: "good morning", want: []string{"Hello!"}},
+		{content: "well, good evening all", want: []string{"Hello!"}},
+		{content: "good afternoon"},
+		{content: "!ping", want: []string{"pong"}},
+		{content: "pang", want: []string{"pattern"}},
+	}
+	for _, tt := range tests {
+		got := handleMessages(t, config, testMessage("30", tt.content, false))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: sent %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestResponsesYAML(t *testing.T) {
+	weight := 3
+	tests := []struct {
+		name    string
+		yaml    string
+		want    Responses
+		wantErr bool
+	}{
+		{name: "string", yaml: "pong", want: Responses{{Text: "pong"}}},
+		{name: "list of strings", yaml: "[ping, pong]", want: Responses{{Text: "ping"}, {Text: "pong"}}},
+		{
+			name: "embed",
+			yaml: "{title: Rules, description: Be nice, color: \"#ff0000\", url: \"https://example.com\"}",
+			want: Responses{{Embed: &Embed{Title: "Rules", Description: "Be nice", Color: "#ff0000", URL: "https://example.com"}}},
+		},
+		{name: "weighted text", yaml: "{text: pong, weight: 3}", want: Responses{{Text: "pong", Weight: &weight}}},
+		{name: "text and embed", yaml: "{text: pong, title: Rules}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Responses
+			err := yaml.UnmarshalStrict([]byte(tt.yaml), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedResponse(t *testing.T) {
+	cmd, err := newCommand("rules", CommandConfig{Output: Responses{{Embed: &Embed{Title: "Rules", Description: "Hi {{.User}}", Color: "#ff0000"}}}}, false)
+	if err != nil {
+		t.Fatalf("newCommand: %v", err)
+	}
+	vals, err := cmd.respond(TemplateData{User: "user"})
+	if err != nil {
+		t.Fatalf("respond: %v", err)
+	}
+	want := &discordgo.MessageEmbed{Title: "Rules", Description: "Hi user", Color: 0xff0000}
+	if len(vals[0].Embeds) != 1 || !reflect.DeepEqual(vals[0].Embeds[0], want) {
+		t.Errorf("embeds = %+v, want %+v", vals[0].Embeds, want)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		want    int
+		wantErr bool
+	}{
+		{color: "16711680", want: 0xff0000},
+		{color: "#00ff00", want: 0x00ff00},
+		{color: "0x0000FF", want: 0x0000ff},
+		{color: "red", wantErr: true},
+		{color: "#1000000", wantErr: true},
+		{color: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.color)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseColor(%q) = %d, %v; want %d, error %v", tt.color, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseEmoji(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "👍", want: "👍"},
+		{in: " 👍 ", want: "👍"},
+		{in: "party:123", want: "party:123"},
+		{in: "<:party:123>", want: "party:123"},
+		{in: "<a:party:123>", want: "party:123"},
+		{in: "", wantErr: true},
+		{in: "party:", wantErr: true},
+		{in: ":123", wantErr: true},
+		{in: "party:abc", wantErr: true},
+		{in: "<:party>", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseEmoji(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEmoji(%q) error = %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseEmoji(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReactionAndResponse(t *testing.T) {
+	fake := &fakeMessenger{}
+	handleWith(t, fake, "prefix: \"!\"\ncommands:\n  party:\n    reaction: \"<:party:123>\"\n    output: Party time\n",
+		testMessage("2", "!party", false))
+
+	if want := []string{"party:123"}; !reflect.DeepEqual(fake.reactions, want) {
+		t.Errorf("reactions = %q, want %q", fake.reactions, want)
+	}
+	sent := fake.messages()
+	if len(sent) != 1 || sent[0].Content != "Party time" {
+		t.Errorf("sent %+v, want one message %q", sent, "Party time")
+	}
+}
+
+func TestPickResponseWeighted(t *testing.T) {
+	config := useConfig(t, "commands:\n  loot:\n    - text: common\n      weight: 6\n    - text: uncommon\n      weight: 3\n    - rare\n")
+	responses := config.lookup["loot"].Responses
+	want := map[string]float64{"common": 0.6, "uncommon": 0.3, "rare": 0.1}
+
+	const draws = 100000
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		message, err := pickResponse(responses, rng.Intn).render(TemplateData{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[message.Content]++
+	}
+	for text, share := range want {
+		got := float64(counts[text]) / draws
+		if math.Abs(got-share) > 0.01 {
+			t.Errorf("%q chosen %.3f of the time, want %.2f", text, got, share)
+		}
+	}
+}
+
+func TestSequenceMode(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\ncommands:\n  tour:\n    mode: sequence\n    output: [Welcome, \"Type !help for commands\", \"Have fun, {{.User}}\"]\n  pick:\n    output: [a, b, c]\n")
+	tour := config.lookup["tour"]
+	if !tour.Sequence || tour.SequenceDelay != defaultSequenceDelay {
+		t.Errorf("sequence = %t with delay %v, want true with %v", tour.Sequence, tour.SequenceDelay, defaultSequenceDelay)
+	}
+	if config.lookup["pick"].Sequence {
+		t.Error("command without a mode is a sequence")
+	}
+
+	// Don't wait between responses.
+	tour.SequenceDelay = time.Millisecond
+	resetTrackers()
+	fake := &fakeMessenger{}
+	h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true}
+	h.Handle(testMessage("30", "!tour", false))
+	h.Handle(testMessage("31", "!pick", false))
+
+	var sent []string
+	for _, msg := range fake.messages() {
+		sent = append(sent, msg.Content)
+	}
+	if len(sent) != 4 {
+		t.Fatalf("sent %q, want the three responses of the sequence and one other", sent)
+	}
+	if want := []string{"Welcome", "Type !help for commands", "Have fun, user-30"}; !reflect.DeepEqual(sent[:3], want) {
+		t.Errorf("sequence sent %q, want %q", sent[:3], want)
+	}
+}
+
+func TestAllowedFor(t *testing.T) {
+	user := func(id string) *discordgo.User { return &discordgo.User{ID: id} }
+	withRole := &discordgo.Member{Roles: []string{"50"}}
+	withoutRole := &discordgo.Member{Roles: []string{"49"}}
+	tests := []struct {
+		name    string
+		cmd     *Command
+		guildID string
+		author  *discordgo.User
+		member  *discordgo.Member
+		want    bool
+	}{
+		{name: "unrestricted", cmd: &Command{}, guildID: "10", author: user("31"), want: true},
+		{name: "allowed user", cmd: &Command{Users: []string{"30"}}, guildID: "10", author: user("30"), want: true},
+		{name: "other user", cmd: &Command{Users: []string{"30"}}, guildID: "10", author: user("31"), member: withRole},
+		{name: "allowed role", cmd: &Command{Users: []string{"30"}, Roles: []string{"50"}}, guildID: "10", author: user("31"), member: withRole, want: true},
+		{name: "other role", cmd: &Command{Roles: []string{"50"}}, guildID: "10", author: user("31"), member: withoutRole},
+		{name: "role in a DM", cmd: &Command{Roles: []string{"50"}}, author: user("31"), member: withRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.allowedFor(&fakeMessenger{}, tt.guildID, tt.author, tt.member); got != tt.want {
+				t.Errorf("allowedFor = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleCommandUsers(t *testing.T) {
+	sent := handleMessages(t, "prefix: \"!\"\nwhitelist_enabled: true\nwhitelist: [\"30\", \"31\"]\ncommands:\n  ping: pong\n  shutdown:\n    output: Bye\n    users: [\"30\"]\n",
+		testMessage("31", "!shutdown", false),
+		testMessage("31", "!ping", false),
+		testMessage("30", "!shutdown", false),
+	)
+	if want := []string{"pong", "Bye"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// confirmEmoji is the reaction that confirms a command.
+	confirmEmoji = "✅"
+	// cancelEmoji is the reaction that cancels a command.
+	cancelEmoji = "❌"
+	// defaultConfirmTimeout is how long to wait for a confirmation if
+	// confirm_timeout isn't set.
+	defaultConfirmTimeout = 30 * time.Second
+)
+
+// choice is the outcome of asking for a confirmation.
+type choice int
+
+const (
+	choiceTimeout choice = iota
+	choiceConfirm
+	choiceCancel
+)
+
+// Confirmations holds the confirmations being waited for.
+var Confirmations = newConfirmationTracker()
+
+// pendingConfirmation is a confirmation being waited for.
+type pendingConfirmation struct {
+	userID string
+	choice chan choice
+}
+
+// confirmationTracker matches reactions to the confirmations waiting for
+// them.
+type confirmationTracker struct {
+	mu      sync.Mutex
+	pending map[string]*pendingConfirmation
+}
+
+// newConfirmationTracker returns an empty confirmationTracker.
+func newConfirmationTracker() *confirmationTracker {
+	return &confirmationTracker{pending: make(map[string]*pendingConfirmation)}
+}
+
+// register starts waiting for the user to react to the message with the
+// confirm or cancel emoji. The result must be passed to wait.
+func (c *confirmationTracker) register(messageID, userID string) *pendingConfirmation {
+	p := &pendingConfirmation{userID: userID, choice: make(chan choice, 1)}
+	c.mu.Lock()
+	c.pending[messageID] = p
+	c.mu.Unlock()
+	return p
+}
+
+// wait waits for the registered confirmation of the message, for at most
+// timeout.
+func (c *confirmationTracker) wait(messageID string, p *pendingConfirmation, timeout time.Duration) choice {
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, messageID)
+		c.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ch := <-p.choice:
+		return ch
+	case <-timer.C:
+		return choiceTimeout
+	}
+}
+
+// deliver passes a reaction to the confirmation waiting for it, if any, and
+// reports whether there was one. Reactions by anyone but the user asked,
+// including the bot's own, and with other emoji are ignored.
+func (c *confirmationTracker) deliver(messageID, userID, emoji string) bool {
+	var ch choice
+	switch emoji {
+	case confirmEmoji:
+		ch = choiceConfirm
+	case cancelEmoji:
+		ch = choiceCancel
+	default:
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[messageID]
+	if !ok || p.userID != userID {
+		return false
+	}
+	select {
+	case p.choice <- ch:
+	default:
+		// The user already chose.
+	}
+	return true
+}
+
+// confirm posts text in the channel, adds the confirm and cancel reactions
+// to it, and waits for the user to click one, for at most timeout. On
+// timeout, the reactions are removed.
+func confirm(s Messenger, config *Config, channelID, userID, text string, timeout time.Duration) (choice, error) {
+	ctx, cancel := requestContext(config)
+	err := waitToSend(ctx, Limiter, config.RateLimitMode)
+	var message *discordgo.Message
+	if err == nil {
+		message, err = sendMessage(s, config, channelID, &discordgo.MessageSend{Content: text}, discordgo.WithContext(ctx))
+	}
+	cancel()
+	if err != nil {
+		return choiceTimeout, err
+	}
+
+	// Start waiting before adding reactions, so that clicks on the first
+	// reaction aren't missed.
+	p := Confirmations.register(message.ID, userID)
+	for _, emoji := range []string{confirmEmoji, cancelEmoji} {
+		ctx, cancel := requestContext(config)
+		err := s.MessageReactionAdd(channelID, message.ID, emoji, discordgo.WithContext(ctx))
+		cancel()
+		if err != nil {
+			slog.Error("error adding confirmation reaction", "channel_id", channelID, "err", err)
+		}
+	}
+
+	ch := Confirmations.wait(message.ID, p, timeout)
+	if ch == choiceTimeout {
+		ctx, cancel := requestContext(config)
+		err := s.MessageReactionsRemoveAll(channelID, message.ID, discordgo.WithContext(ctx))
+		cancel()
+		if err != nil {
+			slog.Warn("error removing confirmation reactions", "channel_id", channelID, "err", err)
+		}
+	}
+	return ch, nil
+}
+
+// confirmTimeout returns how long to wait for the command to be confirmed.
+func (c *Command) confirmTimeout() time.Duration {
+	if c.ConfirmTimeout <= 0 {
+		return defaultConfirmTimeout
+	}
+	return c.ConfirmTimeout
+}
+
+// needsReactions reports whether any command asks for confirmations, which
+// need reaction events.
+func (c *Config) needsReactions() bool {
+	for _, cmd := range c.Commands {
+		if cmd.Confirm != "" && cmd.enabled() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfirmationTracker(t *testing.T) {
+	tests := []struct {
+		name      string
+		messageID string
+		userID    string
+		emoji     string
+		want      choice
+	}{
+		{name: "confirm", messageID: "100", userID: "30", emoji: confirmEmoji, want: choiceConfirm},
+		{name: "cancel", messageID: "100", userID: "30", emoji: cancelEmoji, want: choiceCancel},
+		{name: "other user", messageID: "100", userID: "31", emoji: confirmEmoji, want: choiceTimeout},
+		{name: "other emoji", messageID: "100", userID: "30", emoji: "👍", want: choiceTimeout},
+		{name: "other message", messageID: "101", userID: "30", emoji: confirmEmoji, want: choiceTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfirmationTracker()
+			p := c.register("100", "30")
+			delivered := c.deliver(tt.messageID, tt.userID, tt.emoji)
+			if delivered != (tt.want != choiceTimeout) {
+				t.Errorf("deliver = %t, want %t", delivered, tt.want != choiceTimeout)
+			}
+			if got := c.wait("100", p, 10*time.Millisecond); got != tt.want {
+				t.Errorf("wait = %d, want %d", got, tt.want)
+			}
+			if c.deliver("100", "30", confirmEmoji) {
+				t.Error("deliver after waiting = true, want the confirmation gone")
+			}
+		})
+	}
+}
+
+func TestConfirmationTrackerFirstChoiceWins(t *testing.T) {
+	c := newConfirmationTracker()
+	p := c.register("100", "30")
+	c.deliver("100", "30", cancelEmoji)
+	c.deliver("100", "30", confirmEmoji)
+	if got := c.wait("100", p, time.Second); got != choiceCancel {
+		t.Errorf("wait = %d, want cancel", got)
+	}
+}
+
+// deliverWhenPending delivers the reaction to the message once a
+// confirmation is waiting for it.
+func deliverWhenPending(t *testing.T, messageID, userID, emoji string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !Confirmations.deliver(messageID, userID, emoji) {
+		if time.Now().After(deadline) {
+			t.Fatal("no confirmation was waited for")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	Confirmations = newConfirmationTracker()
+	config := useConfig(t, "prefix: \"!\"\n")
+
+	t.Run("confirm", func(t *testing.T) {
+		fake := &fakeMessenger{}
+		done := make(chan choice)
+		go func() {
+			ch, err := confirm(fake, config, "20", "30", "Sure?", 5*time.Second)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- ch
+		}()
+		deliverWhenPending(t, "100", "30", confirmEmoji)
+		if got := <-done; got != choiceConfirm {
+			t.Errorf("confirm = %d, want confirm", got)
+		}
+		if sent := fake.messages(); len(sent) != 1 || sent[0].Content != "Sure?" {
+			t.Errorf("sent %+v, want the question", sent)
+		}
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if want := []string{confirmEmoji, cancelEmoji}; !reflect.DeepEqual(fake.reactions, want) {
+			t.Errorf("reacted with %q, want %q", fake.reactions, want)
+		}
+		if len(fake.cleared) != 0 {
+			t.Errorf("cleared reactions from %q, want none", fake.cleared)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		fake := &fakeMessenger{}
+		ch, err := confirm(fake, config, "20", "30", "Sure?", 10*time.Millisecond)
+		if err != nil || ch != choiceTimeout {
+			t.Errorf("confirm = %d, %v, want timeout", ch, err)
+		}
+		if want := []string{"100"}; !reflect.DeepEqual(fake.cleared, want) {
+			t.Errorf("cleared reactions from %q, want %q", fake.cleared, want)
+		}
+	})
+}
+
+func TestHandleConfirmedCommand(t *testing.T) {
+	const config = "prefix: \"!\"\ncommands:\n  purge:\n    output: Purged\n    confirm: Really purge?\n"
+	tests := []struct {
+		name  string
+		emoji string
+		want  []string
+	}{
+		{name: "confirmed", emoji: confirmEmoji, want: []string{"Really purge?", "Purged"}},
+		{name: "cancelled", emoji: cancelEmoji, want: []string{"Really purge?"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Confirmations = newConfirmationTracker()
+			useConfig(t, config)
+			resetTrackers()
+			fake := &fakeMessenger{}
+
+			// Confirmations are waited for in the background, so the
+			// handler mustn't be synchronous.
+			h := &MessageHandler{Session: fake, BotID: "1"}
+			h.Handle(testMessage("30", "!purge", false))
+			deliverWhenPending(t, "100", "30", tt.emoji)
+
+			var got []string
+			for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+				got = got[:0]
+				for _, m := range fake.messages() {
+					got = append(got, m.Content)
+				}
+				Confirmations.mu.Lock()
+				pending := len(Confirmations.pending)
+				Confirmations.mu.Unlock()
+				if (pending == 0 && len(got) >= len(tt.want)) || time.Now().After(deadline) {
+					break
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+const (
+	// defaultConnectRetries is how many times connecting to Discord is
+	// retried if connect_retries isn't set.
+	defaultConnectRetries = 5
+	// defaultConnectRetryDelay is the delay in seconds before the first retry
+	// if connect_retry_delay isn't set.
+	defaultConnectRetryDelay = 1
+	// maxConnectRetryDelay is the longest delay between retries.
+	maxConnectRetryDelay = 5 * time.Minute
+)
+
+// connectRetryDelay returns the delay before the first retry.
+func (c *Config) connectRetryDelay() time.Duration {
+	return time.Duration(c.ConnectRetryDelay) * time.Second
+}
+
+// connectBackoff returns the delay before the given retry, counting from 1.
+// The delay starts at base and doubles with each retry, up to
+// maxConnectRetryDelay.
+func connectBackoff(base time.Duration, retry int) time.Duration {
+	delay := base
+	for i := 1; i < retry && delay < maxConnectRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxConnectRetryDelay {
+		delay = maxConnectRetryDelay
+	}
+	return delay
+}
+
+// openWithRetry calls open until it succeeds, retrying up to retries times
+// with backoff starting at base. It returns the last error if every attempt
+// fails.
+func openWithRetry(open func() error, retries int, base time.Duration, sleep func(time.Duration)) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = open()
+		if err == nil {
+			return nil
+		}
+		if attempt > retries {
+			slog.Error("error opening connection", "attempt", attempt, "err", err)
+			return err
+		}
+		delay := connectBackoff(base, attempt)
+		slog.Warn("error opening connection; retrying", "attempt", attempt, "delay", delay, "err", err)
+		sleep(delay)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConnectBackoff(t *testing.T) {
+	tests := []struct {
+		base  time.Duration
+		retry int
+		want  time.Duration
+	}{
+		{base: time.Second, retry: 1, want: time.Second},
+		{base: time.Second, retry: 2, want: 2 * time.Second},
+		{base: time.Second, retry: 5, want: 16 * time.Second},
+		{base: time.Second, retry: 9, want: 256 * time.Second},
+		{base: time.Second, retry: 10, want: maxConnectRetryDelay},
+		{base: time.Second, retry: 1000, want: maxConnectRetryDelay},
+		{base: 10 * time.Minute, retry: 1, want: maxConnectRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := connectBackoff(tt.base, tt.retry); got != tt.want {
+			t.Errorf("connectBackoff(%v, %d) = %v, want %v", tt.base, tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestOpenWithRetry(t *testing.T) {
+	errOpen := errors.New("connection refused")
+	tests := []struct {
+		name       string
+		failures   int
+		retries    int
+		wantErr    bool
+		wantOpens  int
+		wantSleeps []time.Duration
+	}{
+		{name: "first attempt", retries: 3, wantOpens: 1},
+		{name: "second attempt", failures: 1, retries: 3, wantOpens: 2, wantSleeps: []time.Duration{time.Second}},
+		{
+			name:       "gives up",
+			failures:   10,
+			retries:    3,
+			wantErr:    true,
+			wantOpens:  4,
+			wantSleeps: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{name: "no retries", failures: 1, wantErr: true, wantOpens: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opens := 0
+			open := func() error {
+				opens++
+				if opens <= tt.failures {
+					return errOpen
+				}
+				return nil
+			}
+			var sleeps []time.Duration
+			err := openWithRetry(open, tt.retries, time.Second, func(d time.Duration) { sleeps = append(sleeps, d) })
+			if tt.wantErr != errors.Is(err, errOpen) || (!tt.wantErr && err != nil) {
+				t.Errorf("openWithRetry error = %v, want error %t", err, tt.wantErr)
+			}
+			if opens != tt.wantOpens {
+				t.Errorf("opened %d times, want %d", opens, tt.wantOpens)
+			}
+			if !reflect.DeepEqual(sleeps, tt.wantSleeps) {
+				t.Errorf("slept %v, want %v", sleeps, tt.wantSleeps)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// emptyContentThreshold is how many guild messages in a row must have no
+// content before the message content intent is assumed to be missing.
+const emptyContentThreshold = 10
+
+// ContentCheck detects whether the message content intent is missing.
+var ContentCheck = &contentDetector{threshold: emptyContentThreshold}
+
+// contentDetector detects whether the bot can read messages. Without the
+// privileged message content intent, guild messages arrive with no content,
+// so many in a row suggest the intent is missing.
+type contentDetector struct {
+	threshold int
+
+	mu sync.Mutex
+	// empty counts the guild messages in a row with no content.
+	empty int
+	// missing defines if the intent is believed to be missing.
+	missing bool
+}
+
+// observe records whether m has content, and reports whether the intent has
+// just been found to be missing. DMs always have content, and messages with
+// only attachments, embeds, or stickers may legitimately have none, so they
+// are skipped.
+func (d *contentDetector) observe(m *discordgo.Message) bool {
+	if m.GuildID == "" {
+		return false
+	}
+	if m.Content == "" && (len(m.Attachments) > 0 || len(m.Embeds) > 0 || len(m.StickerItems) > 0) {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if m.Content != "" {
+		d.empty = 0
+		d.missing = false
+		return false
+	}
+	d.empty++
+	if d.empty < d.threshold || d.missing {
+		return false
+	}
+	d.missing = true
+	return true
+}
+
+// isMissing reports whether the intent is believed to be missing.
+func (d *contentDetector) isMissing() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.missing
+}
+
+// checkContent records whether m has content, warning if the message
+// content intent appears to be missing.
+func checkContent(m *discordgo.Message) {
+	if ContentCheck.observe(m) {
+		slog.Warn("guild messages have no content; the message content intent may need to be enabled in the developer portal and added to intents",
+			"messages", ContentCheck.threshold)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestContentDetector(t *testing.T) {
+	empty := &discordgo.Message{GuildID: "10"}
+	text := &discordgo.Message{GuildID: "10", Content: "hi"}
+	tests := []struct {
+		name string
+		msgs []*discordgo.Message
+		// want is how many observations report the intent just went
+		// missing.
+		want        int
+		wantMissing bool
+	}{
+		{name: "below threshold", msgs: []*discordgo.Message{empty, empty}},
+		{name: "at threshold", msgs: []*discordgo.Message{empty, empty, empty}, want: 1, wantMissing: true},
+		{name: "warns once", msgs: []*discordgo.Message{empty, empty, empty, empty, empty}, want: 1, wantMissing: true},
+		{name: "content resets count", msgs: []*discordgo.Message{empty, empty, text, empty, empty}},
+		{name: "content clears missing", msgs: []*discordgo.Message{empty, empty, empty, text}, want: 1},
+		{name: "warns again after recovering", msgs: []*discordgo.Message{empty, empty, empty, text, empty, empty, empty}, want: 2, wantMissing: true},
+		{name: "DMs skipped", msgs: []*discordgo.Message{{}, {}, {}}},
+		{name: "attachments skipped", msgs: []*discordgo.Message{
+			{GuildID: "10", Attachments: []*discordgo.MessageAttachment{{ID: "1"}}},
+			{GuildID: "10", Embeds: []*discordgo.MessageEmbed{{Title: "hi"}}},
+			{GuildID: "10", StickerItems: []*discordgo.StickerItem{{ID: "1"}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &contentDetector{threshold: 3}
+			got := 0
+			for _, m := range tt.msgs {
+				if d.observe(m) {
+					got++
+				}
+			}
+			if got != tt.want || d.isMissing() != tt.wantMissing {
+				t.Errorf("warned %d times, missing %t, want %d times, missing %t", got, d.isMissing(), tt.want, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestReadyzMissingContent(t *testing.T) {
+	previous := CurrentConfig.Load()
+	t.Cleanup(func() {
+		Ready.Store(false)
+		CurrentConfig.Store(previous)
+		ContentCheck = &contentDetector{threshold: emptyContentThreshold}
+	})
+	Ready.Store(true)
+	CurrentConfig.Store(&Config{})
+	ContentCheck = &contentDetector{threshold: 1}
+	ContentCheck.observe(&discordgo.Message{GuildID: "10"})
+
+	rec := httptest.NewRecorder()
+	newHealthMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// cooldownPruneInterval is how often expired cooldowns are removed.
+const cooldownPruneInterval = time.Minute
+
+const (
+	// cooldownScopeGlobal puts a command on cooldown for everyone.
+	cooldownScopeGlobal = "global"
+	// cooldownScopeUser puts a command on cooldown for the user who used it.
+	cooldownScopeUser = "user"
+	// cooldownScopeChannel puts a command on cooldown in the channel it was
+	// used in.
+	cooldownScopeChannel = "channel"
+)
+
+// cooldownKey identifies a user's use of a command.
+type cooldownKey struct {
+	UserID  string
+	Command string
+}
+
+// cooldownEntry records a user's last use of a command.
+type cooldownEntry struct {
+	last time.Time
+	// notified defines if the user has been told about the cooldown since
+	// their last use.
+	notified bool
+}
+
+// cooldownTracker records when users last triggered commands.
+type cooldownTracker struct {
+	mu   sync.Mutex
+	last map[cooldownKey]*cooldownEntry
+}
+
+// newCooldownTracker returns an empty cooldownTracker.
+func newCooldownTracker() *cooldownTracker {
+	return &cooldownTracker{last: make(map[cooldownKey]*cooldownEntry)}
+}
+
+// allow reports whether the user may trigger the command at now, given the
+// cooldown duration. If so, now is recorded as the user's last use.
+// Otherwise, the time remaining until the cooldown expires is returned.
+func (c *cooldownTracker) allow(userID, command string, cooldown time.Duration, now time.Time) (time.Duration, bool) {
+	if cooldown <= 0 {
+		return 0, true
+	}
+
+	key := cooldownKey{UserID: userID, Command: command}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.last[key]; ok && now.Sub(entry.last) < cooldown {
+		return cooldown - now.Sub(entry.last), false
+	}
+	c.last[key] = &cooldownEntry{last: now}
+	return 0, true
+}
+
+// ready reports whether the user may trigger the command at now, given the
+// cooldown duration, without recording a use.
+func (c *cooldownTracker) ready(userID, command string, cooldown time.Duration, now time.Time) bool {
+	if cooldown <= 0 {
+		return true
+	}
+
+	key := cooldownKey{UserID: userID, Command: command}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.last[key]
+	return !ok || now.Sub(entry.last) >= cooldown
+}
+
+// notify reports whether the user should be told they are on cooldown for
+// the command. It returns true at most once per use of the command.
+func (c *cooldownTracker) notify(userID, command string) bool {
+	key := cooldownKey{UserID: userID, Command: command}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.last[key]
+	if !ok || entry.notified {
+		return false
+	}
+	entry.notified = true
+	return true
+}
+
+// prune removes entries whose cooldown has expired at now.
+func (c *cooldownTracker) prune(cooldown time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.last {
+		if now.Sub(entry.last) >= cooldown {
+			delete(c.last, key)
+		}
+	}
+}
+
+// isCooldownExempt reports whether the user bypasses cooldowns. Admins are
+// exempt so they can test commands freely.
+func isCooldownExempt(config *Config, userID string) bool {
+	return isAdmin(config, userID)
+}
+
+// checkCooldown reports whether the user, who has the given roles, may
+// trigger the command now, and records the use if so. Otherwise, the time
+// remaining on the user's cooldown is returned.
+func checkCooldown(config *Config, userID string, roles []string, command string) (time.Duration, bool) {
+	if isCooldownExempt(config, userID) {
+		return 0, true
+	}
+	return Cooldowns.allow(userID, command, config.userCooldown(roles), time.Now())
+}
+
+// memberRoles returns the IDs of the member's roles, or nil if there is no
+// member info, as in DMs.
+func memberRoles(member *discordgo.Member) []string {
+	if member == nil {
+		return nil
+	}
+	return member.Roles
+}
+
+// cooldownScopeKey returns the key the command's cooldown is tracked under
+// for a use by the user in the channel, given the command's cooldown scope.
+// The scope defaults to cooldownScopeUser.
+func cooldownScopeKey(scope, userID, channelID string) string {
+	switch scope {
+	case cooldownScopeGlobal:
+		return ""
+	case cooldownScopeChannel:
+		return "channel:" + channelID
+	}
+	return "user:" + userID
+}
+
+// commandReady reports whether the command is off its cooldown for a use by
+// the user in the channel, within the command's cooldown scope.
+func commandReady(config *Config, cmd *Command, userID, channelID string) bool {
+	key := cooldownScopeKey(cmd.CooldownScope, userID, channelID)
+	return CommandCooldowns.ready(key, cmd.Name, cmd.commandCooldown(config), time.Now())
+}
+
+// startCommandCooldown records a use of the command by the user in the
+// channel for its cooldown, within the command's cooldown scope. It returns
+// false if the command has gone on cooldown since commandReady was called.
+func startCommandCooldown(config *Config, cmd *Command, userID, channelID string) bool {
+	key := cooldownScopeKey(cmd.CooldownScope, userID, channelID)
+	_, ok := CommandCooldowns.allow(key, cmd.Name, cmd.commandCooldown(config), time.Now())
+	return ok
+}
+
+// cooldownNotice renders the cooldown message for a user with the given time
+// remaining on their cooldown. It returns false if there is no message or the
+// user has already been told about this cooldown.
+func cooldownNotice(config *Config, userID, command string, remaining time.Duration, data TemplateData) (string, bool) {
+	if config.cooldownMessage == nil || !Cooldowns.notify(userID, command) {
+		return "", false
+	}
+
+	data.Remaining = secondsLeft(remaining)
+	text, err := render(config.cooldownMessage, data)
+	if err != nil {
+		slog.Error("error rendering cooldown message", "command", command, "err", err)
+		return "", false
+	}
+	return text, true
+}
+
+// secondsLeft returns d in whole seconds, rounded up.
+func secondsLeft(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// pruneCooldowns periodically removes expired cooldowns and quota uses,
+// until ctx is done.
+func pruneCooldowns(ctx context.Context) {
+	ticker := time.NewTicker(cooldownPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			config := CurrentConfig.Load()
+			Cooldowns.prune(config.maxUserCooldown(), now)
+			CommandCooldowns.prune(config.maxCommandCooldown(), now)
+			Quotas.prune(config.userQuotaWindow(), now)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCooldownScopeKey(t *testing.T) {
+	tests := []struct {
+		scope string
+		want  string
+	}{
+		{scope: "", want: "user:30"},
+		{scope: cooldownScopeUser, want: "user:30"},
+		{scope: cooldownScopeChannel, want: "channel:20"},
+		{scope: cooldownScopeGlobal, want: ""},
+	}
+	for _, tt := range tests {
+		if got := cooldownScopeKey(tt.scope, "30", "20"); got != tt.want {
+			t.Errorf("cooldownScopeKey(%q) = %q, want %q", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestCommandCooldownScopes(t *testing.T) {
+	// Each use is by a user in a channel, and ready is whether it is allowed
+	// after the first use, by user 30 in channel 20.
+	type use struct {
+		userID, channelID string
+		ready             bool
+	}
+	tests := []struct {
+		scope string
+		uses  []use
+	}{
+		{scope: "", uses: []use{{"30", "20", false}, {"31", "20", true}, {"30", "21", false}}},
+		{scope: cooldownScopeUser, uses: []use{{"30", "20", false}, {"31", "20", true}, {"30", "21", false}}},
+		{scope: cooldownScopeChannel, uses: []use{{"30", "20", false}, {"31", "20", false}, {"30", "21", true}}},
+		{scope: cooldownScopeGlobal, uses: []use{{"30", "20", false}, {"31", "20", false}, {"30", "21", false}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			CommandCooldowns = newCooldownTracker()
+			config := &Config{}
+			cmd := &Command{Name: "ping", Cooldown: time.Minute, CooldownScope: tt.scope}
+			if !startCommandCooldown(config, cmd, "30", "20") {
+				t.Fatal("first use was refused")
+			}
+			for _, u := range tt.uses {
+				if got := commandReady(config, cmd, u.userID, u.channelID); got != u.ready {
+					t.Errorf("commandReady(user %s, channel %s) = %v, want %v", u.userID, u.channelID, got, u.ready)
+				}
+			}
+		})
+	}
+}
+
+func TestCooldownTrackerAllow(t *testing.T) {
+	c := newCooldownTracker()
+	now := time.Now()
+
+	if _, ok := c.allow("30", "ping", time.Minute, now); !ok {
+		t.Fatal("first use was refused")
+	}
+	remaining, ok := c.allow("30", "ping", time.Minute, now.Add(time.Second))
+	if ok {
+		t.Fatal("second use within the cooldown was allowed")
+	}
+	if remaining != 59*time.Second {
+		t.Errorf("remaining = %v, want %v", remaining, 59*time.Second)
+	}
+	if _, ok := c.allow("31", "ping", time.Minute, now.Add(time.Second)); !ok {
+		t.Error("use by another user was refused")
+	}
+	if _, ok := c.allow("30", "pong", time.Minute, now.Add(time.Second)); !ok {
+		t.Error("use of another command was refused")
+	}
+	if _, ok := c.allow("30", "ping", time.Minute, now.Add(time.Minute)); !ok {
+		t.Error("use after the cooldown expired was refused")
+	}
+}
+
+func TestCooldownTrackerPrune(t *testing.T) {
+	c := newCooldownTracker()
+	now := time.Now()
+	c.allow("30", "ping", time.Minute, now)
+	c.allow("31", "ping", time.Minute, now.Add(30*time.Second))
+
+	c.prune(time.Minute, now.Add(time.Minute))
+	if _, ok := c.last[cooldownKey{UserID: "30", Command: "ping"}]; ok {
+		t.Error("expired cooldown wasn't pruned")
+	}
+	if _, ok := c.last[cooldownKey{UserID: "31", Command: "ping"}]; !ok {
+		t.Error("active cooldown was pruned")
+	}
+}
+
+func TestSecondsLeft(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{d: 0, want: 0},
+		{d: time.Millisecond, want: 1},
+		{d: time.Second, want: 1},
+		{d: 1500 * time.Millisecond, want: 2},
+		{d: time.Minute, want: 60},
+	}
+	for _, tt := range tests {
+		if got := secondsLeft(tt.d); got != tt.want {
+			t.Errorf("secondsLeft(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCooldownNoticeOncePerWindow(t *testing.T) {
+	const config = "prefix: \"!\"\ncooldown: 60\ncooldown_message: \"Wait {{.Remaining}}s, {{.User}}\"\ncommands:\n  ping: pong\n"
+	sent := handleMessages(t, config,
+		testMessage("30", "!ping", false),
+		testMessage("30", "!ping", false),
+		testMessage("30", "!ping", false),
+		testMessage("31", "!ping", false),
+	)
+	want := []string{"pong", "Wait 60s, user-30", "pong"}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
+
+func TestIsCooldownExempt(t *testing.T) {
+	config := &Config{Admins: []string{"30"}}
+	if !isCooldownExempt(config, "30") {
+		t.Error("admin is not exempt")
+	}
+	if isCooldownExempt(config, "31") {
+		t.Error("user who isn't an admin is exempt")
+	}
+	if isCooldownExempt(&Config{}, "30") {
+		t.Error("user is exempt with no admins")
+	}
+}
+
+func TestCheckCooldownAdmin(t *testing.T) {
+	Cooldowns = newCooldownTracker()
+	config := &Config{Cooldown: 60, Admins: []string{"30"}}
+	for _, userID := range []string{"30", "31"} {
+		if _, ok := checkCooldown(config, userID, nil, "ping"); !ok {
+			t.Fatalf("first use by %s throttled", userID)
+		}
+	}
+	if _, ok := checkCooldown(config, "30", nil, "ping"); !ok {
+		t.Error("admin throttled during the cooldown")
+	}
+	if left, ok := checkCooldown(config, "31", nil, "ping"); ok || left <= 0 {
+		t.Errorf("user not throttled during the cooldown: %v, %t", left, ok)
+	}
+}
+
+func TestCommandCooldownAcrossUsers(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name:   "global",
+			config: "prefix: \"!\"\ncommand_cooldown: 60\ncommands:\n  ping:\n    output: pong\n    cooldown_scope: global\n  hi: hello\n",
+			want:   []string{"pong", "hello", "hello"},
+		},
+		{
+			name:   "per command",
+			config: "prefix: \"!\"\ncommands:\n  ping:\n    output: pong\n    command_cooldown: 60\n    cooldown_scope: global\n  hi: hello\n",
+			want:   []string{"pong", "hello", "hello"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := handleMessages(t, tt.config,
+				testMessage("30", "!ping", false),
+				testMessage("31", "!ping", false),
+				testMessage("30", "!hi", false),
+				testMessage("31", "!hi", false),
+			)
+			if !reflect.DeepEqual(sent, tt.want) {
+				t.Errorf("sent %q, want %q", sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserCooldown(t *testing.T) {
+	config := &Config{Cooldown: 60, RoleCooldowns: map[string]int{"booster": 10, "vip": 0, "slow": 120}}
+	tests := []struct {
+		name  string
+		roles []string
+		want  time.Duration
+	}{
+		{name: "no roles", want: time.Minute},
+		{name: "no matching roles", roles: []string{"member"}, want: time.Minute},
+		{name: "one matching role", roles: []string{"member", "booster"}, want: 10 * time.Second},
+		{name: "most favorable role", roles: []string{"booster", "vip"}, want: 0},
+		{name: "longer override", roles: []string{"slow"}, want: 2 * time.Minute},
+		{name: "shortest of several", roles: []string{"slow", "booster"}, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.userCooldown(tt.roles); got != tt.want {
+				t.Errorf("userCooldown(%q) = %v, want %v", tt.roles, got, tt.want)
+			}
+		})
+	}
+	if got := config.maxUserCooldown(); got != 2*time.Minute {
+		t.Errorf("maxUserCooldown = %v, want %v", got, 2*time.Minute)
+	}
+}
+
+func TestCooldownNoticeDM(t *testing.T) {
+	const config = "prefix: \"!\"\ncooldown: 60\ncooldown_dm: true\ncooldown_message: \"Wait {{.Remaining}}s\"\ncommands:\n  ping: pong\n"
+	dmsClosed := &discordgo.RESTError{
+		Response: &http.Response{StatusCode: http.StatusForbidden},
+		Message:  &discordgo.APIErrorMessage{Code: discordgo.ErrCodeCannotSendMessagesToThisUser},
+	}
+	tests := []struct {
+		name    string
+		dmErr   error
+		sendErr error
+		want    []sentMessage
+		wantLog string
+	}{
+		{name: "DM", want: []sentMessage{{ChannelID: "20", Content: "pong"}, {ChannelID: "dm-30", Content: "Wait 60s"}}},
+		{name: "DM channel fails", dmErr: restError(http.StatusInternalServerError), want: []sentMessage{{ChannelID: "20", Content: "pong"}}, wantLog: "error opening DM channel"},
+		{name: "DMs closed", sendErr: dmsClosed, want: []sentMessage{{ChannelID: "20", Content: "pong"}}, wantLog: "DMs closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t, "text", slog.LevelInfo)
+			fake := &fakeMessenger{}
+			handleWith(t, fake, config, testMessage("30", "!ping", false))
+
+			// Fail only the cooldown notice.
+			fake.dmErr = tt.dmErr
+			if tt.sendErr != nil {
+				fake.sendErrs = []error{tt.sendErr}
+			}
+			h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true}
+			h.Handle(testMessage("30", "!ping", false))
+
+			for i := range fake.sent {
+				fake.sent[i].Reference = nil
+			}
+			if !reflect.DeepEqual(fake.sent, tt.want) {
+				t.Errorf("sent %+v, want %+v", fake.sent, tt.want)
+			}
+			if tt.wantLog != "" && !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("logs %q don't contain %q", logs, tt.wantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// configDiff summarizes the changes between two configs.
+type configDiff struct {
+	// Added, Removed, and Changed hold the names of commands that were
+	// added, removed, or changed.
+	Added   []string
+	Removed []string
+	Changed []string
+	// WhitelistAdded and WhitelistRemoved hold the IDs of users and roles
+	// that were added to or removed from the whitelist.
+	WhitelistAdded   []string
+	WhitelistRemoved []string
+	// Toggled holds the names of flags that were turned on or off.
+	Toggled []string
+}
+
+// empty reports whether nothing changed.
+func (d configDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 &&
+		len(d.WhitelistAdded) == 0 && len(d.WhitelistRemoved) == 0 && len(d.Toggled) == 0
+}
+
+// diffConfigs returns the changes from old to new.
+func diffConfigs(old, new *Config) configDiff {
+	var d configDiff
+
+	for name, cmd := range new.Commands {
+		oldCmd, ok := old.Commands[name]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case !reflect.DeepEqual(oldCmd, cmd):
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range old.Commands {
+		if _, ok := new.Commands[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+
+	oldWhitelist := append(append([]string(nil), old.Whitelist...), old.WhitelistRoles...)
+	newWhitelist := append(append([]string(nil), new.Whitelist...), new.WhitelistRoles...)
+	d.WhitelistAdded = missingFrom(newWhitelist, oldWhitelist)
+	d.WhitelistRemoved = missingFrom(oldWhitelist, newWhitelist)
+
+	// Compare every flag, named as in the config file.
+	oldVal, newVal := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < oldVal.NumField(); i++ {
+		field := oldVal.Type().Field(i)
+		if field.Type.Kind() != reflect.Bool || !field.IsExported() {
+			continue
+		}
+		if oldVal.Field(i).Bool() != newVal.Field(i).Bool() {
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			d.Toggled = append(d.Toggled, name)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// missingFrom returns the strings in a that aren't in b, sorted.
+func missingFrom(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+
+	var missing []string
+	for _, s := range a {
+		if !in[s] {
+			missing = append(missing, s)
+			in[s] = true
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// logConfigDiff logs the changes from old to new.
+func logConfigDiff(old, new *Config) {
+	d := diffConfigs(old, new)
+	if d.empty() {
+		slog.Info("config reloaded without changes")
+		return
+	}
+	slog.Info("config changed",
+		"commands_added", len(d.Added), "commands_removed", len(d.Removed), "commands_changed", len(d.Changed),
+		"added", d.Added, "removed", d.Removed, "changed", d.Changed,
+		"whitelist_added", d.WhitelistAdded, "whitelist_removed", d.WhitelistRemoved,
+		"toggled", d.Toggled,
+	)
+}
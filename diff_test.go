@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	pong := CommandConfig{Output: Responses{{Text: "pong"}}}
+	old := &Config{
+		Commands: map[string]CommandConfig{
+			"ping":  pong,
+			"hello": {Output: Responses{{Text: "Hello"}}},
+			"bye":   {Output: Responses{{Text: "Bye"}}},
+		},
+		Whitelist:      []string{"30", "31"},
+		WhitelistRoles: []string{"50"},
+		AllowDM:        true,
+	}
+	new := &Config{
+		Commands: map[string]CommandConfig{
+			"ping":  pong,
+			"hello": {Output: Responses{{Text: "Hi there"}}},
+			"joke":  {Output: Responses{{Text: "Knock knock"}}},
+			"dance": {Output: Responses{{Text: "*dances*"}}},
+		},
+		Whitelist:        []string{"31", "32"},
+		WhitelistRoles:   []string{"50", "51"},
+		WhitelistEnabled: true,
+	}
+
+	got := diffConfigs(old, new)
+	want := configDiff{
+		Added:            []string{"dance", "joke"},
+		Removed:          []string{"bye"},
+		Changed:          []string{"hello"},
+		WhitelistAdded:   []string{"32", "51"},
+		WhitelistRemoved: []string{"30"},
+		Toggled:          []string{"whitelist_enabled", "allow_dm"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffConfigs =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiffConfigsUnchanged(t *testing.T) {
+	config := &Config{Commands: map[string]CommandConfig{"ping": {Output: Responses{{Text: "pong"}}}}, Whitelist: []string{"30"}}
+	if d := diffConfigs(config, config); !d.empty() {
+		t.Errorf("diffConfigs of the same config = %+v, want no changes", d)
+	}
+}
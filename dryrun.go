@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// dryRunGuildID is the ID of the guild test messages are sent in.
+	dryRunGuildID = "1"
+	// dryRunChannelID is the ID of the channel test messages are sent in.
+	dryRunChannelID = "2"
+	// dryRunBotID is the bot's user ID when handling test messages.
+	dryRunBotID = "3"
+	// dryRunUserID is the ID of the user test messages are sent by, unless
+	// the whitelist names another.
+	dryRunUserID = "4"
+)
+
+// printMessenger is a Messenger that prints what would be sent to Discord
+// instead of sending it.
+type printMessenger struct {
+	w io.Writer
+	// member is returned as the member info of every user.
+	member *discordgo.Member
+}
+
+func (p *printMessenger) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: channelID, GuildID: dryRunGuildID, Type: discordgo.ChannelTypeGuildText}, nil
+}
+
+func (p *printMessenger) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+func (p *printMessenger) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	p.printMessage("channel "+channelID, data.Content, data.Embeds, data.Files)
+	return &discordgo.Message{ChannelID: channelID, Content: data.Content}, nil
+}
+
+func (p *printMessenger) ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error {
+	fmt.Fprintf(p.w, "[channel %s] (deleted message %s)\n", channelID, messageID)
+	return nil
+}
+
+func (p *printMessenger) ChannelTyping(channelID string, options ...discordgo.RequestOption) error {
+	fmt.Fprintf(p.w, "[channel %s] (typing)\n", channelID)
+	return nil
+}
+
+func (p *printMessenger) MessageThreadStartComplex(channelID, messageID string, data *discordgo.ThreadStart, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	fmt.Fprintf(p.w, "[channel %s] (started thread %q)\n", channelID, data.Name)
+	return &discordgo.Channel{ID: "thread", ParentID: channelID, Type: discordgo.ChannelTypeGuildPublicThread}, nil
+}
+
+func (p *printMessenger) MessageReactionsRemoveAll(channelID, messageID string, options ...discordgo.RequestOption) error {
+	fmt.Fprintf(p.w, "[channel %s] (removed reactions from %s)\n", channelID, messageID)
+	return nil
+}
+
+func (p *printMessenger) MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error {
+	fmt.Fprintf(p.w, "[channel %s] (reacted with %s)\n", channelID, emojiID)
+	return nil
+}
+
+func (p *printMessenger) GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	return p.member, nil
+}
+
+func (p *printMessenger) GuildMemberTimeout(guildID, userID string, until *time.Time, options ...discordgo.RequestOption) error {
+	fmt.Fprintf(p.w, "[guild %s] (timed out user %s until %s)\n", guildID, userID, until.Format(time.RFC3339))
+	return nil
+}
+
+func (p *printMessenger) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	return discordgo.PermissionAll, nil
+}
+
+func (p *printMessenger) WebhookWithToken(webhookID, token string, options ...discordgo.RequestOption) (*discordgo.Webhook, error) {
+	return &discordgo.Webhook{ID: webhookID, ChannelID: dryRunChannelID}, nil
+}
+
+func (p *printMessenger) WebhookExecute(webhookID, token string, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	p.printMessage("webhook "+webhookID, data.Content, data.Embeds, data.Files)
+	return &discordgo.Message{Content: data.Content}, nil
+}
+
+// printMessage prints a message sent to dest.
+func (p *printMessenger) printMessage(dest, content string, embeds []*discordgo.MessageEmbed, files []*discordgo.File) {
+	if content != "" {
+		fmt.Fprintf(p.w, "[%s] %s\n", dest, content)
+	}
+	for _, embed := range embeds {
+		fmt.Fprintf(p.w, "[%s] (embed) %s: %s\n", dest, embed.Title, embed.Description)
+	}
+	for _, file := range files {
+		fmt.Fprintf(p.w, "[%s] (file) %s\n", dest, file.Name)
+	}
+}
+
+// dryRunConfig returns a copy of config with the settings that reach
+// outside of Discord turned off, so that a dry run has no side effects:
+// events aren't posted and uses aren't audited.
+func dryRunConfig(config *Config) *Config {
+	dry := *config
+	dry.EventWebhook = ""
+	dry.AuditChannel = ""
+	return &dry
+}
+
+// dryRun handles content as if it were sent by an approved user, printing
+// what the bot would send to w. Messages and webhooks only go to the
+// printMessenger, and nothing else leaves the process: events and audit
+// messages are turned off, and admin commands don't write to storage.
+func dryRun(w io.Writer, config *Config, content string) {
+	// Handle the message with side effects turned off. The bot exits after a
+	// dry run, so the config and storage are never needed again.
+	config = dryRunConfig(config)
+	CurrentConfig.Store(config)
+	DB = nil
+
+	// Send as the first whitelisted user, with every whitelisted role, so
+	// the message is approved.
+	userID := dryRunUserID
+	if len(config.Whitelist) > 0 {
+		userID = config.Whitelist[0]
+	}
+	member := &discordgo.Member{
+		GuildID: dryRunGuildID,
+		User:    &discordgo.User{ID: userID, Username: "test"},
+		Roles:   config.WhitelistRoles,
+	}
+
+	h := &MessageHandler{
+		Session:     &printMessenger{w: w, member: member},
+		BotID:       dryRunBotID,
+		Synchronous: true,
+	}
+	h.Handle(&discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        "5",
+		ChannelID: dryRunChannelID,
+		GuildID:   dryRunGuildID,
+		Content:   content,
+		Author:    member.User,
+		Member:    member,
+	}})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDryRunHasNoSideEffects(t *testing.T) {
+	var posts atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer server.Close()
+
+	config := useConfig(t, "prefix: \"!\"\naudit_channel: \"40\"\nevent_webhook: "+server.URL+"\ncommands:\n  ping: pong\n")
+	CommandCooldowns = newCooldownTracker()
+	var out bytes.Buffer
+	dryRun(&out, config, "!ping")
+
+	if !strings.Contains(out.String(), "pong") {
+		t.Errorf("output %q doesn't include the response", out.String())
+	}
+	if strings.Contains(out.String(), "[40]") || strings.Contains(out.String(), "ran `ping`") {
+		t.Errorf("output %q includes an audit message", out.String())
+	}
+	// Events are posted in the background, so give one time to arrive.
+	time.Sleep(100 * time.Millisecond)
+	if n := posts.Load(); n != 0 {
+		t.Errorf("event webhook got %d posts, want 0", n)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// respondedTTL is how long messages are remembered as responded to, after
+// which edits to them may trigger commands again.
+const respondedTTL = time.Hour
+
+// respondedTracker records which messages the bot has responded to, so that
+// editing a message doesn't trigger a second response.
+type respondedTracker struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// newRespondedTracker returns an empty respondedTracker.
+func newRespondedTracker() *respondedTracker {
+	return &respondedTracker{seen: make(map[string]time.Time)}
+}
+
+// mark records that the message with the given ID was responded to at now.
+func (r *respondedTracker) mark(messageID string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen[messageID] = now
+}
+
+// has reports whether the message with the given ID was responded to.
+func (r *respondedTracker) has(messageID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.seen[messageID]
+	return ok
+}
+
+// prune removes messages responded to at least ttl before now.
+func (r *respondedTracker) prune(ttl time.Duration, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, at := range r.seen {
+		if now.Sub(at) >= ttl {
+			delete(r.seen, id)
+		}
+	}
+}
+
+// pruneResponded periodically forgets old responded-to messages until ctx is
+// cancelled.
+func pruneResponded(ctx context.Context) {
+	ticker := time.NewTicker(cooldownPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			Responded.prune(respondedTTL, now)
+		}
+	}
+}
+
+func messageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	h := &MessageHandler{Session: s, BotID: s.State.User.ID, AllowSelf: DebugAllowSelf, GuildLocale: stateGuildLocale(s.State)}
+	h.HandleUpdate(m)
+}
+
+// HandleUpdate responds to the edited message m, if edits are handled and it
+// has been edited into a command that hasn't already been responded to.
+func (h *MessageHandler) HandleUpdate(m *discordgo.MessageUpdate) {
+	if !CurrentConfig.Load().HandleEdits {
+		return
+	}
+
+	// Ignore partial updates, such as when links are unfurled, which don't
+	// include the author, and updates that don't change the content.
+	if m.Message == nil || m.Author == nil {
+		return
+	}
+	if m.BeforeUpdate != nil && m.BeforeUpdate.Content == m.Content {
+		return
+	}
+
+	// Don't respond to the same message twice.
+	if Responded.has(m.ID) {
+		return
+	}
+
+	h.Handle(&discordgo.MessageCreate{Message: m.Message})
+}
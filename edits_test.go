@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// edited returns an update of the message m edited to content.
+func edited(m *discordgo.MessageCreate, content string) *discordgo.MessageUpdate {
+	before := *m.Message
+	after := *m.Message
+	after.Content = content
+	return &discordgo.MessageUpdate{Message: &after, BeforeUpdate: &before}
+}
+
+func TestHandleUpdate(t *testing.T) {
+	const config = "prefix: \"!\"\nhandle_edits: true\ncommands:\n  ping: pong\n  hi: hello\n"
+	tests := []struct {
+		name   string
+		config string
+		create *discordgo.MessageCreate
+		update func(m *discordgo.MessageCreate) *discordgo.MessageUpdate
+		want   []string
+	}{
+		{
+			name:   "edited into a command",
+			config: config,
+			create: testMessage("30", "!pnig", false),
+			update: func(m *discordgo.MessageCreate) *discordgo.MessageUpdate { return edited(m, "!ping") },
+			want:   []string{"pong"},
+		},
+		{
+			name:   "already responded to",
+			config: config,
+			create: testMessage("30", "!ping", false),
+			update: func(m *discordgo.MessageCreate) *discordgo.MessageUpdate { return edited(m, "!hi") },
+			want:   []string{"pong"},
+		},
+		{
+			name:   "content unchanged",
+			config: config,
+			create: testMessage("30", "!pnig", false),
+			update: func(m *discordgo.MessageCreate) *discordgo.MessageUpdate { return edited(m, "!pnig") },
+		},
+		{
+			name:   "partial update",
+			config: config,
+			create: testMessage("30", "!pnig", false),
+			update: func(m *discordgo.MessageCreate) *discordgo.MessageUpdate {
+				u := edited(m, "!ping")
+				u.Author = nil
+				return u
+			},
+		},
+		{
+			name:   "own edit",
+			config: config,
+			create: testMessage("1", "!pnig", true),
+			update: func(m *discordgo.MessageCreate) *discordgo.MessageUpdate { return edited(m, "!ping") },
+		},
+		{
+			name:   "edits not handled",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			create: testMessage("30", "!pnig", false),
+			update: func(m *discordgo.MessageCreate) *discordgo.MessageUpdate { return edited(m, "!ping") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			handleWith(t, fake, tt.config, tt.create)
+			h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true}
+			h.HandleUpdate(tt.update(tt.create))
+
+			var got []string
+			for _, msg := range fake.messages() {
+				got = append(got, msg.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondedTrackerPrune(t *testing.T) {
+	r := newRespondedTracker()
+	now := time.Now()
+	r.mark("100", now.Add(-2*time.Hour))
+	r.mark("101", now)
+	r.prune(time.Hour, now)
+	if r.has("100") {
+		t.Error("old message was not pruned")
+	}
+	if !r.has("101") {
+		t.Error("recent message was pruned")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// customEmojiPattern matches a custom emoji as sent in messages: "<:name:id>"
+// for static emoji, or "<a:name:id>" for animated emoji.
+var customEmojiPattern = regexp.MustCompile(`^<(a?):(\w{2,32}):(\d{1,20})>$`)
+
+// customEmoji is a parsed custom emoji.
+type customEmoji struct {
+	Name     string
+	ID       string
+	Animated bool
+}
+
+// parseCustomEmoji parses a custom emoji in the form used in messages, and
+// reports whether s was one.
+func parseCustomEmoji(s string) (customEmoji, bool) {
+	match := customEmojiPattern.FindStringSubmatch(s)
+	if match == nil {
+		return customEmoji{}, false
+	}
+	return customEmoji{Name: match[2], ID: match[3], Animated: match[1] == "a"}, true
+}
+
+// String returns the emoji in the form used in messages.
+func (e customEmoji) String() string {
+	if e.Animated {
+		return "<a:" + e.Name + ":" + e.ID + ">"
+	}
+	return "<:" + e.Name + ":" + e.ID + ">"
+}
+
+// looksLikeCustomEmoji reports whether s appears to be meant as a custom
+// emoji in the form used in messages, whether or not it is well-formed.
+func looksLikeCustomEmoji(s string) bool {
+	return (strings.HasPrefix(s, "<:") || strings.HasPrefix(s, "<a:")) && strings.HasSuffix(s, ">")
+}
+
+// checkEmojiResponse logs a warning if the text of one of the command's
+// responses appears to be a custom emoji but is malformed, since Discord
+// would send it as plain text. Well-formed custom emoji are returned in
+// canonical form, and anything else is returned unchanged.
+func checkEmojiResponse(name, text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !looksLikeCustomEmoji(trimmed) {
+		return text
+	}
+	emoji, ok := parseCustomEmoji(trimmed)
+	if !ok {
+		slog.Warn("response looks like a custom emoji but is malformed; it will be sent as text", "command", name, "emoji", trimmed)
+		return text
+	}
+	return emoji.String()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseCustomEmoji(t *testing.T) {
+	tests := []struct {
+		in   string
+		want customEmoji
+		ok   bool
+	}{
+		{in: "<:party:123>", want: customEmoji{Name: "party", ID: "123"}, ok: true},
+		{in: "<a:party_parrot:456>", want: customEmoji{Name: "party_parrot", ID: "456", Animated: true}, ok: true},
+		{in: "<:p:123>"},
+		{in: "<:party:>"},
+		{in: "<:party:abc>"},
+		{in: "<b:party:123>"},
+		{in: "<:party:123> and more"},
+		{in: "party:123"},
+		{in: "👍"},
+	}
+	for _, tt := range tests {
+		got, ok := parseCustomEmoji(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseCustomEmoji(%q) = %+v, %t, want %+v, %t", tt.in, got, ok, tt.want, tt.ok)
+		}
+		if ok && got.String() != tt.in {
+			t.Errorf("parseCustomEmoji(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestCheckEmojiResponse(t *testing.T) {
+	tests := []struct {
+		text     string
+		want     string
+		wantWarn bool
+	}{
+		{text: "<a:dance:123>", want: "<a:dance:123>"},
+		{text: "  <:dance:123>\n", want: "<:dance:123>"},
+		{text: "<a:dance>", want: "<a:dance>", wantWarn: true},
+		{text: "<:dance:12x>", want: "<:dance:12x>", wantWarn: true},
+		{text: "Hello <3", want: "Hello <3"},
+	}
+	for _, tt := range tests {
+		logs := captureLogs(t, "text", slog.LevelWarn)
+		if got := checkEmojiResponse("dance", tt.text); got != tt.want {
+			t.Errorf("checkEmojiResponse(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if warned := strings.Contains(logs.String(), "malformed"); warned != tt.wantWarn {
+			t.Errorf("checkEmojiResponse(%q) warned = %t, want %t", tt.text, warned, tt.wantWarn)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expandEnv replaces references to environment variables in s, given as
+// "${VAR}", with their values from lookup. "$$" is replaced with "$", so
+// "$${VAR}" gives a literal "${VAR}". Any other "$" is left alone. It is an
+// error to reference an undefined variable.
+func expandEnv(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+
+		switch s[1] {
+		case '$':
+			b.WriteByte('$')
+			s = s[2:]
+		case '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference %q", s)
+			}
+			name := s[2:end]
+			if name == "" {
+				return "", errors.New("empty variable reference")
+			}
+			value, ok := lookup(name)
+			if !ok {
+				return "", fmt.Errorf("undefined environment variable %q", name)
+			}
+			b.WriteString(value)
+			s = s[end+1:]
+		default:
+			b.WriteByte('$')
+			s = s[1:]
+		}
+	}
+}
+
+// expandConfigEnv expands references to environment variables in the
+// config's command outputs, files, and webhook URLs.
+func expandConfigEnv(config *Config, lookup func(string) (string, bool)) error {
+	expand := func(field string, s *string) error {
+		expanded, err := expandEnv(*s, lookup)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		*s = expanded
+		return nil
+	}
+
+	if err := expand("webhook", &config.Webhook); err != nil {
+		return err
+	}
+
+	// Sort keys so errors are reported the same way on every load.
+	keys := make([]string, 0, len(config.Commands))
+	for k := range config.Commands {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		cmd := config.Commands[k]
+		output := make(Responses, len(cmd.Output))
+		copy(output, cmd.Output)
+		for i := range output {
+			field := fmt.Sprintf("command %q: output %d", k, i+1)
+			if output[i].Embed == nil {
+				if err := expand(field, &output[i].Text); err != nil {
+					return err
+				}
+				continue
+			}
+			embed := *output[i].Embed
+			for _, s := range []*string{&embed.Title, &embed.Description, &embed.URL} {
+				if err := expand(field, s); err != nil {
+					return err
+				}
+			}
+			output[i].Embed = &embed
+		}
+		cmd.Output = output
+		if err := expand(fmt.Sprintf("command %q: file", k), &cmd.File); err != nil {
+			return err
+		}
+		if err := expand(fmt.Sprintf("command %q: webhook", k), &cmd.Webhook); err != nil {
+			return err
+		}
+		config.Commands[k] = cmd
+	}
+	return nil
+}
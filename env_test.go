@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"SUPPORT_URL": "https://example.com/help", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: "no variables", want: "no variables"},
+		{in: "See ${SUPPORT_URL}.", want: "See https://example.com/help."},
+		{in: "${SUPPORT_URL}${SUPPORT_URL}", want: "https://example.com/help" + "https://example.com/help"},
+		{in: "[${EMPTY}]", want: "[]"},
+		{in: "Costs $$5", want: "Costs $5"},
+		{in: "Literal $${SUPPORT_URL}", want: "Literal ${SUPPORT_URL}"},
+		{in: "Costs $5 or $", want: "Costs $5 or $"},
+		{in: "$SUPPORT_URL", want: "$SUPPORT_URL"},
+		{in: "${MISSING}", wantErr: "undefined environment variable \"MISSING\""},
+		{in: "${SUPPORT_URL", wantErr: "unterminated"},
+		{in: "${}", wantErr: "empty"},
+	}
+	for _, tt := range tests {
+		got, err := expandEnv(tt.in, lookup)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expandEnv(%q) error = %v, want error containing %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandEnv(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigExpandEnv(t *testing.T) {
+	t.Setenv("SUPPORT_URL", "https://example.com/help")
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{name: "enabled", config: "expand_env: true\ncommands:\n  help: \"See ${SUPPORT_URL}\"\n", want: "See https://example.com/help"},
+		{name: "disabled", config: "commands:\n  help: \"See ${SUPPORT_URL}\"\n", want: "See ${SUPPORT_URL}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := useConfig(t, tt.config)
+			if got := config.Commands["help"].Output[0].Text; got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
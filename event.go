@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// eventRetries is how many times posting an event that fails with a
+	// retryable error is retried.
+	eventRetries = 3
+	// eventRetryDelay is the delay before the first retry.
+	eventRetryDelay = time.Second
+	// eventTimeout is how long each attempt to post an event may take.
+	eventTimeout = 10 * time.Second
+)
+
+// Event is posted as JSON to the event webhook for each handled command.
+type Event struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Command   string    `json:"command"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	ChannelID string    `json:"channel_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// errEventStatus is returned when the event webhook responds with a status
+// that is worth retrying, such as a server error.
+var errEventStatus = errors.New("unexpected status")
+
+// validateEventWebhook returns an error if rawURL isn't an http or https URL.
+func validateEventWebhook(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid event webhook URL %q: must be an http or https URL", rawURL)
+	}
+	return nil
+}
+
+// postEvent posts the event as JSON to rawURL once.
+func postEvent(ctx context.Context, client *http.Client, rawURL string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, eventTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w %s", errEventStatus, resp.Status)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// postEventWithRetry posts the event, retrying network errors and
+// retryable statuses up to eventRetries times.
+func postEventWithRetry(ctx context.Context, client *http.Client, rawURL string, event Event, delay time.Duration) error {
+	for retry := 1; ; retry++ {
+		err := postEvent(ctx, client, rawURL, event)
+		if err == nil || retry > eventRetries {
+			return err
+		}
+
+		// Other statuses mean the request is wrong and won't succeed.
+		var urlErr *url.Error
+		if !errors.Is(err, errEventStatus) && !errors.As(err, &urlErr) {
+			return err
+		}
+		slog.Warn("error posting event; retrying", "retry", retry, "err", err)
+
+		timer := time.NewTimer(delay << (retry - 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// emitEvent posts a use of the command to the event webhook in the
+// background, if one is set. Failures are logged, and never affect handling
+// the command.
+func emitEvent(config *Config, user *discordgo.User, command, guildID, channelID string) {
+	if config.EventWebhook == "" {
+		return
+	}
+
+	event := Event{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Command:   command,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		Timestamp: time.Now().UTC(),
+	}
+	go func() {
+		err := postEventWithRetry(context.Background(), http.DefaultClient, config.EventWebhook, event, eventRetryDelay)
+		if err != nil {
+			slog.Error("error posting event", "command", command, "err", err)
+		}
+	}()
+}
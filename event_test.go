@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// eventServer returns a server that replies to each event posted with the
+// next of statuses, then with 200, and sends each body it receives on the
+// returned channel.
+func eventServer(t *testing.T, statuses ...int) (*httptest.Server, <-chan []byte, *atomic.Int32) {
+	t.Helper()
+	bodies := make(chan []byte, 10)
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(attempts.Add(1))
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies, &attempts
+}
+
+func TestPostEventPayload(t *testing.T) {
+	srv, bodies, _ := eventServer(t)
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		event Event
+		want  map[string]any
+	}{
+		{
+			name:  "guild",
+			event: Event{UserID: "30", Username: "user-30", Command: "ping", GuildID: "10", ChannelID: "20", Timestamp: at},
+			want:  map[string]any{"user_id": "30", "username": "user-30", "command": "ping", "guild_id": "10", "channel_id": "20", "timestamp": "2024-05-01T12:30:00Z"},
+		},
+		{
+			name:  "DM",
+			event: Event{UserID: "30", Username: "user-30", Command: "ping", ChannelID: "21", Timestamp: at},
+			want:  map[string]any{"user_id": "30", "username": "user-30", "command": "ping", "channel_id": "21", "timestamp": "2024-05-01T12:30:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := postEvent(context.Background(), srv.Client(), srv.URL, tt.event); err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(<-bodies, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("posted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostEventWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{name: "success", wantAttempts: 1},
+		{name: "server error retried", statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests}, wantAttempts: 3},
+		{name: "client error not retried", statuses: []int{http.StatusBadRequest}, wantAttempts: 1, wantErr: true},
+		{name: "gives up", statuses: []int{500, 500, 500, 500, 500}, wantAttempts: eventRetries + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _, attempts := eventServer(t, tt.statuses...)
+			err := postEventWithRetry(context.Background(), srv.Client(), srv.URL, Event{Command: "ping"}, time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("postEventWithRetry = %v, want error %t", err, tt.wantErr)
+			}
+			if attempts.Load() != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", attempts.Load(), tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestHandleEmitsEvent(t *testing.T) {
+	srv, bodies, _ := eventServer(t)
+	sent := handleMessages(t, "prefix: \"!\"\nevent_webhook: "+srv.URL+"\ncommands:\n  ping: pong\n", testMessage("30", "!ping", false))
+	if !reflect.DeepEqual(sent, []string{"pong"}) {
+		t.Errorf("sent %q, want %q", sent, []string{"pong"})
+	}
+
+	select {
+	case body := <-bodies:
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.UserID != "30" || event.Username != "user-30" || event.Command != "ping" || event.GuildID != "10" || event.ChannelID != "20" || event.Timestamp.IsZero() {
+			t.Errorf("posted %+v, want the use of ping", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event was posted")
+	}
+}
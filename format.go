@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// decodeConfig decodes data into config, in the format given by the
+// extension of path. YAML is assumed if the extension is unknown. config is
+// usually a *Config, but may be any type with YAML struct tags.
+func decodeConfig(path string, data []byte, config interface{}) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		// Convert TOML to YAML, so the YAML struct tags and unmarshalers
+		// apply to every format.
+		var raw map[string]interface{}
+		err := toml.Unmarshal(data, &raw)
+		if err != nil {
+			return err
+		}
+		data, err = yaml.Marshal(raw)
+		if err != nil {
+			return err
+		}
+	case ".json":
+		// JSON is valid YAML, so it can be decoded as is.
+	}
+
+	return yaml.UnmarshalStrict(data, config)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "prefix: \"!\"\ncooldown: 5\nwhitelist: [\"30\"]\ncommands:\n  ping: pong\n  rules:\n    output: [Be nice, Be kind]\n    channels: [\"20\"]\n",
+		"config.yml":  "prefix: \"!\"\ncooldown: 5\nwhitelist: [\"30\"]\ncommands:\n  ping: pong\n  rules:\n    output: [Be nice, Be kind]\n    channels: [\"20\"]\n",
+		"config.json": `{"prefix": "!", "cooldown": 5, "whitelist": ["30"], "commands": {"ping": "pong", "rules": {"output": ["Be nice", "Be kind"], "channels": ["20"]}}}`,
+		"config.toml": "prefix = \"!\"\ncooldown = 5\nwhitelist = [\"30\"]\n[commands]\nping = \"pong\"\n[commands.rules]\noutput = [\"Be nice\", \"Be kind\"]\nchannels = [\"20\"]\n",
+		"config.conf": "prefix: \"!\"\ncooldown: 5\nwhitelist: [\"30\"]\ncommands:\n  ping: pong\n  rules:\n    output: [Be nice, Be kind]\n    channels: [\"20\"]\n",
+	}
+	want := Config{
+		Prefix:    Prefixes{"!"},
+		Cooldown:  5,
+		Whitelist: []string{"30"},
+		Commands: map[string]CommandConfig{
+			"ping":  {Output: Responses{{Text: "pong"}}},
+			"rules": {Output: Responses{{Text: "Be nice"}, {Text: "Be kind"}}, Channels: []string{"20"}},
+		},
+	}
+	for path, data := range files {
+		t.Run(path, func(t *testing.T) {
+			var got Config
+			if err := decodeConfig(path, []byte(data), &got); err != nil {
+				t.Fatalf("decodeConfig: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log/slog"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultSuggestThreshold is the largest edit distance at which a command is
+// suggested if no threshold is set.
+const defaultSuggestThreshold = 2
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Only two rows of the distance matrix are needed at a time.
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// suggestCommand returns the command name closest to name, if it is within
+// the configured threshold.
+func suggestCommand(config *Config, name string) (string, bool) {
+	threshold := config.SuggestThreshold
+	if threshold <= 0 {
+		threshold = defaultSuggestThreshold
+	}
+
+	// Sort names so ties are broken the same way every time.
+	names := make([]string, 0, len(config.lookup)+3)
+	for key := range config.lookup {
+		names = append(names, key)
+	}
+	for _, builtin := range []string{config.HelpCommand, config.StatsCommand, config.StatusCommand} {
+		if builtin != "" {
+			names = append(names, builtin)
+		}
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", threshold+1
+	for _, candidate := range names {
+		// Don't suggest commands that share nothing with name.
+		d := levenshtein(name, candidate)
+		if d >= len([]rune(candidate)) {
+			continue
+		}
+		if d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best, best != ""
+}
+
+// sendSuggestion suggests a command close to name, used with the given
+// prefix, to the author of m, if there is one, and reports whether there was.
+func sendSuggestion(s Messenger, m *discordgo.MessageCreate, config *Config, prefix, name string) bool {
+	suggestion, ok := suggestCommand(config, name)
+	if !ok {
+		return false
+	}
+
+	err := sendResponse(s, m, config, &discordgo.MessageSend{
+		Content: "Did you mean `" + prefix + suggestion + "`?",
+	})
+	if err != nil {
+		slog.Error("error sending suggestion", "command", name, "err", err)
+	}
+	return true
+}
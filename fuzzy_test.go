@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"ping", "", 4},
+		{"", "ping", 4},
+		{"ping", "ping", 0},
+		{"ping", "pong", 1},
+		{"ping", "pnig", 2},
+		{"ping", "pings", 1},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestCommand(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\nsuggest_commands: true\nhelp_command: help\ncommands:\n  ping: pong\n  weather: Sunny\n")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "pnig", want: "ping"},
+		{name: "wether", want: "weather"},
+		{name: "hlep", want: "help"},
+		{name: "xyz"},
+		{name: "completely different"},
+		{name: "p"},
+	}
+	for _, tt := range tests {
+		got, ok := suggestCommand(config, tt.name)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("suggestCommand(%q) = %q, %t, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestSuggestCommandThreshold(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\nsuggest_commands: true\nsuggest_threshold: 1\ncommands:\n  weather: Sunny\n")
+	if got, ok := suggestCommand(config, "weathr"); !ok || got != "weather" {
+		t.Errorf("near miss suggested %q, %t, want %q", got, ok, "weather")
+	}
+	if got, ok := suggestCommand(config, "wethr"); ok {
+		t.Errorf("miss beyond the threshold suggested %q", got)
+	}
+}
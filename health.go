@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// healthShutdownTimeout is how long the health server has to finish
+// in-flight requests when shutting down.
+const healthShutdownTimeout = 5 * time.Second
+
+var (
+	// Connected reports whether the Discord session is connected.
+	Connected atomic.Bool
+	// Ready reports whether the bot has connected to Discord with a config
+	// loaded, and is ready to handle commands.
+	Ready atomic.Bool
+)
+
+func connect(s *discordgo.Session, c *discordgo.Connect) {
+	Connected.Store(true)
+}
+
+func disconnect(s *discordgo.Session, d *discordgo.Disconnect) {
+	Connected.Store(false)
+}
+
+// healthHandler returns a handler that responds with 200 OK if ok reports
+// true, or 503 Service Unavailable otherwise.
+func healthHandler(ok func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ok() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		// There's nothing to do if the client has gone away.
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+// newHealthMux returns a handler serving /healthz, which reports whether
+// the session is connected, and /readyz, which reports whether the bot is
+// ready. The bot isn't ready if it can't read message content. Metrics are
+// served on /metrics.
+func newHealthMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", healthHandler(Connected.Load))
+	mux.Handle("/readyz", healthHandler(func() bool {
+		return Ready.Load() && CurrentConfig.Load() != nil && !ContentCheck.isMissing()
+	}))
+	mux.HandleFunc("/metrics", metricsHandler)
+	return mux
+}
+
+// startHealthServer serves health checks on addr in the background.
+func startHealthServer(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: newHealthMux(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("error serving health checks", "err", err)
+		}
+	}()
+	slog.Info("serving health checks", "addr", listener.Addr().String())
+	return server, nil
+}
+
+// stopHealthServer shuts down the health server, if it is running.
+func stopHealthServer(server *http.Server) {
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthShutdownTimeout)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if err != nil {
+		slog.Error("error stopping health server", "err", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthMux(t *testing.T) {
+	tests := []struct {
+		name        string
+		connected   bool
+		ready       bool
+		config      *Config
+		wantHealthz int
+		wantReadyz  int
+	}{
+		{name: "starting", wantHealthz: http.StatusServiceUnavailable, wantReadyz: http.StatusServiceUnavailable},
+		{name: "connected", connected: true, config: &Config{}, wantHealthz: http.StatusOK, wantReadyz: http.StatusServiceUnavailable},
+		{name: "ready", connected: true, ready: true, config: &Config{}, wantHealthz: http.StatusOK, wantReadyz: http.StatusOK},
+		{name: "no config", connected: true, ready: true, wantHealthz: http.StatusOK, wantReadyz: http.StatusServiceUnavailable},
+		{name: "disconnected", ready: true, config: &Config{}, wantHealthz: http.StatusServiceUnavailable, wantReadyz: http.StatusOK},
+	}
+	previous := CurrentConfig.Load()
+	t.Cleanup(func() {
+		Connected.Store(false)
+		Ready.Store(false)
+		CurrentConfig.Store(previous)
+	})
+	ContentCheck = &contentDetector{threshold: emptyContentThreshold}
+
+	mux := newHealthMux()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Connected.Store(tt.connected)
+			Ready.Store(tt.ready)
+			CurrentConfig.Store(tt.config)
+			for path, want := range map[string]int{"/healthz": tt.wantHealthz, "/readyz": tt.wantReadyz} {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != want {
+					t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"path/filepath"
+)
+
+// includeFile is a config file included by another. Only commands and
+// further includes may be given.
+type includeFile struct {
+	Commands map[string]CommandConfig `yaml:"commands"`
+	Include  []string                 `yaml:"include"`
+}
+
+// mergeIncludes merges the commands of the files included by the config at
+// path into the config. Paths are relative to the directory of the file that
+// includes them. Later files override earlier ones, and included files
+// override the main one.
+func mergeIncludes(config *Config, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return mergeIncludeList(config, abs, config.Include, map[string]bool{abs: true})
+}
+
+// mergeIncludeList merges the commands of the files in includes, given by
+// the file at path, into the config. including holds the files currently
+// being included, to detect cycles.
+func mergeIncludeList(config *Config, path string, includes []string, including map[string]bool) error {
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		include = filepath.Clean(include)
+		if including[include] {
+			return fmt.Errorf("include %q: include cycle", include)
+		}
+
+		data, err := ioutil.ReadFile(include)
+		if err != nil {
+			return fmt.Errorf("include %q: %w", include, err)
+		}
+		var file includeFile
+		err = decodeConfig(include, data, &file)
+		if err != nil {
+			return fmt.Errorf("include %q: %w", include, err)
+		}
+
+		if config.Commands == nil {
+			config.Commands = make(map[string]CommandConfig, len(file.Commands))
+		}
+		for name, cmd := range file.Commands {
+			if _, exists := config.Commands[name]; exists {
+				slog.Warn("included command overrides an earlier one", "command", name, "include", include)
+			}
+			config.Commands[name] = cmd
+		}
+
+		including[include] = true
+		err = mergeIncludeList(config, include, file.Include, including)
+		delete(including, include)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
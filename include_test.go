@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the files, keyed by path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"commands/fun.yaml":   "commands:\n  joke: Knock knock\n  ping: pong from fun\ninclude: [more.yaml]\n",
+		"commands/more.yaml":  "commands:\n  dance: \"*dances*\"\n",
+		"commands/admin.yaml": "commands:\n  ping: pong from admin\n",
+	})
+	config := &Config{
+		Commands: map[string]CommandConfig{"ping": {Output: Responses{{Text: "pong"}}}, "help": {Output: Responses{{Text: "Help"}}}},
+		Include:  []string{"commands/fun.yaml", filepath.Join(dir, "commands/admin.yaml")},
+	}
+	if err := mergeIncludes(config, filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"help":  "Help",
+		"joke":  "Knock knock",
+		"dance": "*dances*",
+		"ping":  "pong from admin",
+	}
+	if len(config.Commands) != len(want) {
+		t.Errorf("got %d commands, want %d", len(config.Commands), len(want))
+	}
+	for name, text := range want {
+		if got := config.Commands[name].Output; len(got) != 1 || got[0].Text != text {
+			t.Errorf("command %q = %+v, want %q", name, got, text)
+		}
+	}
+}
+
+func TestMergeIncludesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		include string
+		wantErr string
+	}{
+		{name: "missing", include: "missing.yaml", wantErr: "missing.yaml"},
+		{name: "invalid", files: map[string]string{"bad.yaml": "commands: [\n"}, include: "bad.yaml", wantErr: "bad.yaml"},
+		{name: "unknown field", files: map[string]string{"bad.yaml": "token: secret\n"}, include: "bad.yaml", wantErr: "token"},
+		{
+			name:    "cycle",
+			files:   map[string]string{"a.yaml": "include: [b.yaml]\n", "b.yaml": "include: [a.yaml]\n"},
+			include: "a.yaml",
+			wantErr: "include cycle",
+		},
+		{name: "self", files: map[string]string{"a.yaml": "include: [a.yaml]\n"}, include: "a.yaml", wantErr: "include cycle"},
+		{name: "main config", files: map[string]string{"a.yaml": "include: [config.yaml]\n"}, include: "a.yaml", wantErr: "include cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			config := &Config{Include: []string{tt.include}}
+			err := mergeIncludes(config, filepath.Join(dir, "config.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("mergeIncludes error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeIncludesShared(t *testing.T) {
+	// Including the same file twice, but not within itself, isn't a cycle.
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml":      "include: [shared.yaml]\n",
+		"b.yaml":      "include: [shared.yaml]\n",
+		"shared.yaml": "commands:\n  ping: pong\n",
+	})
+	config := &Config{Include: []string{"a.yaml", "b.yaml"}}
+	if err := mergeIncludes(config, filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.Commands["ping"]; !ok {
+		t.Error("shared command not included")
+	}
+}
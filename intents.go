@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultIntents are the intents used if none are configured.
+var defaultIntents = []string{"guild_messages", "message_content"}
+
+// intentNames maps the names used in the config to intents.
+var intentNames = map[string]discordgo.Intent{
+	"guilds":                   discordgo.IntentsGuilds,
+	"guild_members":            discordgo.IntentsGuildMembers,
+	"guild_bans":               discordgo.IntentsGuildBans,
+	"guild_emojis":             discordgo.IntentsGuildEmojis,
+	"guild_integrations":       discordgo.IntentsGuildIntegrations,
+	"guild_webhooks":           discordgo.IntentsGuildWebhooks,
+	"guild_invites":            discordgo.IntentsGuildInvites,
+	"guild_voice_states":       discordgo.IntentsGuildVoiceStates,
+	"guild_presences":          discordgo.IntentsGuildPresences,
+	"guild_messages":           discordgo.IntentsGuildMessages,
+	"guild_message_reactions":  discordgo.IntentsGuildMessageReactions,
+	"guild_message_typing":     discordgo.IntentsGuildMessageTyping,
+	"direct_messages":          discordgo.IntentsDirectMessages,
+	"direct_message_reactions": discordgo.IntentsDirectMessageReactions,
+	"direct_message_typing":    discordgo.IntentsDirectMessageTyping,
+	"message_content":          discordgo.IntentsMessageContent,
+	"guild_scheduled_events":   discordgo.IntentsGuildScheduledEvents,
+}
+
+// resolveIntents returns the named intents OR'd together, or the default
+// intents if names is empty. Unknown names are logged and ignored.
+func resolveIntents(names []string) discordgo.Intent {
+	if len(names) == 0 {
+		names = defaultIntents
+	}
+
+	var intents discordgo.Intent
+	for _, name := range names {
+		intent, ok := intentNames[name]
+		if !ok {
+			slog.Warn("unknown intent; ignoring it", "intent", name)
+			continue
+		}
+		intents |= intent
+	}
+	return intents
+}
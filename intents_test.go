@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestResolveIntents(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  discordgo.Intent
+	}{
+		{name: "default", want: discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent},
+		{name: "one", names: []string{"guilds"}, want: discordgo.IntentsGuilds},
+		{
+			name:  "several",
+			names: []string{"guild_messages", "guild_members", "direct_messages"},
+			want:  discordgo.IntentsGuildMessages | discordgo.IntentsGuildMembers | discordgo.IntentsDirectMessages,
+		},
+		{name: "repeated", names: []string{"guilds", "guilds"}, want: discordgo.IntentsGuilds},
+		{name: "unknown ignored", names: []string{"guilds", "guild_everything"}, want: discordgo.IntentsGuilds},
+		{name: "only unknown", names: []string{"GUILDS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveIntents(tt.names); got != tt.want {
+				t.Errorf("resolveIntents(%q) = %d, want %d", tt.names, got, tt.want)
+			}
+		})
+	}
+}
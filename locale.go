@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Translations maps locale codes, such as "fr" or "pt-BR", to command names
+// to translated outputs.
+type Translations map[string]map[string]Responses
+
+// stateGuildLocale returns a function that looks up the preferred locale of
+// guilds in state.
+func stateGuildLocale(state *discordgo.State) func(guildID string) string {
+	return func(guildID string) string {
+		guild, err := state.Guild(guildID)
+		if err != nil {
+			return ""
+		}
+		return guild.PreferredLocale
+	}
+}
+
+// addLocales parses the command's translated outputs, if any.
+func (c *Command) addLocales(locales Translations) error {
+	for locale, commands := range locales {
+		output, ok := commands[c.Name]
+		if !ok || len(output) == 0 {
+			continue
+		}
+		parsed := make([]*response, 0, len(output))
+		for _, r := range output {
+			p, err := newResponse(c.Name, r)
+			if err != nil {
+				return fmt.Errorf("locale %q: %w", locale, err)
+			}
+			parsed = append(parsed, p)
+		}
+		if c.Localized == nil {
+			c.Localized = make(map[string][]*response)
+		}
+		c.Localized[strings.ToLower(locale)] = parsed
+	}
+	return nil
+}
+
+// responsesFor returns the command's responses in the given locale, falling
+// back to its default responses if there is no translation.
+func (c *Command) responsesFor(locale string) []*response {
+	if key, ok := resolveLocale(c.Localized, locale); ok {
+		return c.Localized[key]
+	}
+	return c.Responses
+}
+
+// resolveLocale returns the key in available that best matches locale, such
+// as "pt-br" for "pt-BR", or the language alone, "pt", if there is no
+// translation for the region. Keys in available must be lowercase.
+func resolveLocale(available map[string][]*response, locale string) (string, bool) {
+	if locale == "" || len(available) == 0 {
+		return "", false
+	}
+	locale = strings.ToLower(locale)
+	if _, ok := available[locale]; ok {
+		return locale, true
+	}
+	if language, _, hasRegion := strings.Cut(locale, "-"); hasRegion {
+		if _, ok := available[language]; ok {
+			return language, true
+		}
+	}
+	return "", false
+}
+
+// validateLocales returns all problems with the config's translated
+// outputs.
+func validateLocales(config Config) []error {
+	var errs []error
+
+	// Sort keys so errors are reported in the same order on every load.
+	locales := make([]string, 0, len(config.Locales))
+	for locale := range config.Locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	for _, locale := range locales {
+		names := make([]string, 0, len(config.Locales[locale]))
+		for name := range config.Locales[locale] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if _, exists := config.Commands[name]; !exists {
+				errs = append(errs, fmt.Errorf("locale %q: unknown command %q", locale, name))
+			}
+			for i, r := range config.Locales[locale][name] {
+				if isBlank(r) {
+					errs = append(errs, fmt.Errorf("locale %q: command %q: output %d is blank", locale, name, i+1))
+				}
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestResolveLocale(t *testing.T) {
+	available := map[string][]*response{"fr": nil, "pt-br": nil}
+	tests := []struct {
+		locale string
+		want   string
+		ok     bool
+	}{
+		{locale: "fr", want: "fr", ok: true},
+		{locale: "fr-CA", want: "fr", ok: true},
+		{locale: "pt-BR", want: "pt-br", ok: true},
+		{locale: "pt-PT"},
+		{locale: "de"},
+		{locale: ""},
+	}
+	for _, tt := range tests {
+		got, ok := resolveLocale(available, tt.locale)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("resolveLocale(%q) = %q, %t, want %q, %t", tt.locale, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLocalizedResponses(t *testing.T) {
+	config := useConfig(t, "commands:\n  hello: Hello\n  bye: Bye\nlocales:\n  fr:\n    hello: Bonjour\n  pt-BR:\n    hello: Olá\n")
+	tests := []struct {
+		name    string
+		command string
+		locale  string
+		want    string
+	}{
+		{name: "translation", command: "hello", locale: "fr", want: "Bonjour"},
+		{name: "region", command: "hello", locale: "pt-BR", want: "Olá"},
+		{name: "language of region", command: "hello", locale: "fr-CA", want: "Bonjour"},
+		{name: "missing translation", command: "bye", locale: "fr", want: "Bye"},
+		{name: "unknown locale", command: "hello", locale: "de", want: "Hello"},
+		{name: "no locale", command: "hello", want: "Hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vals, err := config.lookup[tt.command].respond(TemplateData{Locale: tt.locale})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := vals[0].Content; got != tt.want {
+				t.Errorf("response = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleGuildLocale(t *testing.T) {
+	useConfig(t, "prefix: \"!\"\ncommands:\n  hello: Hello\nlocales:\n  fr:\n    hello: Bonjour\n")
+	resetTrackers()
+	fake := &fakeMessenger{}
+	h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true, GuildLocale: func(guildID string) string {
+		if guildID == "10" {
+			return "fr"
+		}
+		return ""
+	}}
+	h.Handle(testMessage("30", "!hello", false))
+	if sent := fake.messages(); len(sent) != 1 || sent[0].Content != "Bonjour" {
+		t.Errorf("sent %+v, want %q", sent, "Bonjour")
+	}
+}
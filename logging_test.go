@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// captureLogs sends logs at the level to a buffer in the format until the
+// test ends.
+func captureLogs(t *testing.T, format string, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newLogger(&buf, format, level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestJSONLogging(t *testing.T) {
+	logs := captureLogs(t, "json", slog.LevelDebug)
+	cmd := &Command{Name: "ping"}
+	cmd.logHandled("30", "20")
+
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("log line %q isn't JSON: %v", logs.String(), err)
+	}
+	want := map[string]string{"msg": "command handled", "command": "ping", "author_id": "30", "channel_id": "20"}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %q", key, line[key], value)
+		}
+	}
+}
+
+func TestTextLogging(t *testing.T) {
+	logs := captureLogs(t, "", slog.LevelInfo)
+	slog.Info("hello", "command", "ping")
+	if got := logs.String(); !strings.Contains(got, "msg=hello") || !strings.Contains(got, "command=ping") {
+		t.Errorf("log line %q isn't in text format", got)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+		ok   bool
+	}{
+		{in: "", want: slog.LevelInfo, ok: true},
+		{in: "debug", want: slog.LevelDebug, ok: true},
+		{in: " INFO ", want: slog.LevelInfo, ok: true},
+		{in: "warning", want: slog.LevelWarn, ok: true},
+		{in: "error", want: slog.LevelError, ok: true},
+		{in: "verbose", want: slog.LevelInfo},
+	}
+	for _, tt := range tests {
+		got, ok := parseLogLevel(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseLogLevel(%q) = %v, %t, want %v, %t", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHandledLogLevel(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name  string
+		level slog.Level
+		log   *bool
+		want  bool
+	}{
+		{name: "debug line at debug level", level: slog.LevelDebug, want: true},
+		{name: "debug line at info level", level: slog.LevelInfo},
+		{name: "logged command at info level", level: slog.LevelInfo, log: &yes, want: true},
+		{name: "unlogged command at debug level", level: slog.LevelDebug, log: &no},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t, "text", tt.level)
+			cmd := &Command{Name: "ping", Log: tt.log}
+			cmd.logHandled("30", "20")
+			if got := strings.Contains(logs.String(), "command handled"); got != tt.want {
+				t.Errorf("logged = %t, want %t: %q", got, tt.want, logs)
+			}
+		})
+	}
+}
+
+func TestHandledLogPerCommand(t *testing.T) {
+	logs := captureLogs(t, "text", slog.LevelDebug)
+	handleMessages(t, "prefix: \"!\"\ncommands:\n  ping:\n    output: Pong!\n  noisy:\n    output: Spam\n    log: false\n",
+		testMessage("30", "!ping", false), testMessage("30", "!noisy", false))
+
+	got := logs.String()
+	if !strings.Contains(got, "command=ping") {
+		t.Errorf("logs %q don't include ping, want it logged", got)
+	}
+	if strings.Contains(got, "command=noisy") {
+		t.Errorf("logs %q include noisy, want it unlogged", got)
+	}
+}
@@ -1,158 +1,1425 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"io/ioutil"
-	"log"
+	"log/slog"
+	"math/rand"
+	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"text/template"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"gopkg.in/yaml.v2"
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/time/rate"
 )
 
 var (
-	// Token is the Discord API token.
+	// Version is the bot's version, set at build time with
+	// -ldflags "-X main.Version=...".
+	Version = "dev"
+	// StartTime is when the bot started.
+	StartTime time.Time
+	// Reloads counts how many times the config has been reloaded.
+	Reloads atomic.Int64
+	// Token is the Discord API token the session is using.
 	Token string
-	// Commands is a map of commands and their outputs.
-	Commands map[string]string
-	// WhitelistEnabled defines if only approved users may use bot commands.
-	WhitelistEnabled bool
-	// Whitelist is a slice of user IDs approved to use bot commands.
-	Whitelist []string
+	// Tokens are the tokens to try connecting with, in order.
+	Tokens []string
+	// TokenFile is the path of a file holding the token, if set. It is
+	// read again whenever the config is reloaded on SIGHUP.
+	TokenFile string
+	// ConfigPath is the path of the config file.
+	ConfigPath string
+	// ValidateOnly defines if the bot should exit after loading the config,
+	// without connecting to Discord.
+	ValidateOnly bool
+	// TestMessage is a message to print the response to, without connecting
+	// to Discord, if set.
+	TestMessage string
+	// DebugAllowSelf defines if the bot handles its own messages, for test
+	// setups where it is driven by its own account.
+	DebugAllowSelf bool
+	// CurrentConfig holds the config in use. It is replaced as a whole when
+	// the config is reloaded, so it should be loaded once and the same
+	// snapshot used throughout handling an event.
+	CurrentConfig atomic.Pointer[Config]
+	// Remote fetches commands from an HTTP endpoint, if STORAGE is "http".
+	Remote *remoteCommands
+	// RemoteRefresh is how often commands are fetched from Remote, if set.
+	RemoteRefresh time.Duration
+	// DB is the database commands are stored in, if STORAGE is "sqlite".
+	// Otherwise, commands are read from the config file.
+	DB *sql.DB
+	// ConfigMu serializes config loads.
+	ConfigMu sync.Mutex
+	// Cooldowns records when users last triggered commands.
+	Cooldowns = newCooldownTracker()
+	// CommandCooldowns records when commands were last triggered by anyone.
+	CommandCooldowns = newCooldownTracker()
+	// Responded records which messages have been responded to, if edits are
+	// handled.
+	Responded = newRespondedTracker()
+	// Quotas records how many commands users have triggered in each guild.
+	Quotas = newQuotaTracker()
+	// Limiter limits the rate of outbound messages.
+	Limiter = rate.NewLimiter(rate.Inf, 1)
+	// SendQueue queues responses to be sent by workers, if enabled.
+	SendQueue *sendQueue
+	// Scheduler sends scheduled messages, once the Discord session is open.
+	Scheduler *scheduler
+	// PresenceRotator shows the bot's status, once the Discord session is
+	// open.
+	PresenceRotator *presenceRotator
+	// Stats counts how many times each command has been used.
+	Stats = newCommandStats()
 	// ConfigLoaded defines if the config has been loaded.
 	ConfigLoaded bool
 )
 
-// Config defines the YAML config data structure.
+// Config defines the config data structure. It is decoded from YAML, JSON, or
+// TOML using its YAML struct tags.
 type Config struct {
-	Commands         map[string]string `yaml:"commands"`
-	WhitelistEnabled bool              `yaml:"whitelist_enabled"`
-	Whitelist        []string          `yaml:"whitelist"`
+	SchemaVersion         int                      `yaml:"version"`
+	Commands              map[string]CommandConfig `yaml:"commands"`
+	WhitelistEnabled      bool                     `yaml:"whitelist_enabled"`
+	Whitelist             []string                 `yaml:"whitelist"`
+	WhitelistRoles        []string                 `yaml:"whitelist_roles"`
+	Blacklist             []string                 `yaml:"blacklist"`
+	AllowedChannels       []string                 `yaml:"allowed_channels"`
+	Prefix                Prefixes                 `yaml:"prefix"`
+	CaseInsensitive       bool                     `yaml:"case_insensitive"`
+	Cooldown              int                      `yaml:"cooldown"`
+	CooldownMessage       string                   `yaml:"cooldown_message"`
+	CooldownDM            bool                     `yaml:"cooldown_dm"`
+	CommandCooldown       int                      `yaml:"command_cooldown"`
+	RoleCooldowns         map[string]int           `yaml:"role_cooldowns"`
+	WatchConfig           bool                     `yaml:"watch_config"`
+	TypingIndicator       bool                     `yaml:"typing_indicator"`
+	TypingMaxDelay        int                      `yaml:"typing_max_delay"`
+	Reply                 bool                     `yaml:"reply"`
+	HelpCommand           string                   `yaml:"help_command"`
+	SlashCommands         bool                     `yaml:"slash_commands"`
+	AllowDM               bool                     `yaml:"allow_dm"`
+	StatsCommand          string                   `yaml:"stats_command"`
+	StatsTop              int                      `yaml:"stats_top"`
+	StatsReset            int                      `yaml:"stats_reset"`
+	Admins                []string                 `yaml:"admins"`
+	MessagesPerSecond     float64                  `yaml:"messages_per_second"`
+	RateLimitMode         string                   `yaml:"rate_limit_mode"`
+	SendWorkers           int                      `yaml:"send_workers"`
+	SendQueueSize         int                      `yaml:"send_queue_size"`
+	SendQueuePolicy       string                   `yaml:"send_queue_policy"`
+	Schedules             []Schedule               `yaml:"schedules"`
+	WelcomeChannel        string                   `yaml:"welcome_channel"`
+	WelcomeMessage        string                   `yaml:"welcome_message"`
+	OnlineChannel         string                   `yaml:"online_channel"`
+	OnlineMessage         string                   `yaml:"online_message"`
+	AnnounceReconnects    bool                     `yaml:"announce_reconnects"`
+	RequireMention        bool                     `yaml:"require_mention"`
+	SuggestCommands       bool                     `yaml:"suggest_commands"`
+	SuggestThreshold      int                      `yaml:"suggest_threshold"`
+	Webhook               string                   `yaml:"webhook"`
+	DeleteTrigger         bool                     `yaml:"delete_trigger"`
+	IgnoreBots            bool                     `yaml:"ignore_bots"`
+	Intents               []string                 `yaml:"intents"`
+	AuditChannel          string                   `yaml:"audit_channel"`
+	EventWebhook          string                   `yaml:"event_webhook"`
+	StatusCommand         string                   `yaml:"status_command"`
+	ShardID               int                      `yaml:"shard_id"`
+	ShardCount            int                      `yaml:"shard_count"`
+	UnknownCommandMessage string                   `yaml:"unknown_command_message"`
+	ConnectRetries        int                      `yaml:"connect_retries"`
+	ConnectRetryDelay     int                      `yaml:"connect_retry_delay"`
+	ExpandEnv             bool                     `yaml:"expand_env"`
+	RequestTimeout        int                      `yaml:"request_timeout"`
+	Locales               Translations             `yaml:"locales"`
+	HandleEdits           bool                     `yaml:"handle_edits"`
+	ResponsePipeline      []string                 `yaml:"response_pipeline"`
+	ResponsePrefix        string                   `yaml:"response_prefix"`
+	ResponseSuffix        string                   `yaml:"response_suffix"`
+	ReactionRoles         ReactionRoles            `yaml:"reaction_roles"`
+	Presence              Presences                `yaml:"presence"`
+	PresenceInterval      int                      `yaml:"presence_interval"`
+	Include               []string                 `yaml:"include"`
+	BannedWords           []string                 `yaml:"banned_words"`
+	MinAccountAgeDays     int                      `yaml:"min_account_age_days"`
+	MinMembershipDays     int                      `yaml:"min_membership_days"`
+	Timezone              string                   `yaml:"timezone"`
+	UserQuota             int                      `yaml:"user_quota"`
+	UserQuotaWindow       int                      `yaml:"user_quota_window"`
+	QuotaTimeout          int                      `yaml:"quota_timeout"`
+	QuotaTimeoutStrikes   int                      `yaml:"quota_timeout_strikes"`
+
+	// lookup is the map used to match messages to commands. Its keys are
+	// lowercased if CaseInsensitive is set.
+	lookup map[string]*Command
+	// cooldownMessage is the parsed CooldownMessage, if set.
+	cooldownMessage *template.Template
+	// webhook is the parsed Webhook, if set.
+	webhook *Webhook
+	// welcomeMessage is the parsed WelcomeMessage, if set.
+	welcomeMessage *template.Template
+	// onlineMessage is the parsed OnlineMessage, if set.
+	onlineMessage *template.Template
+	// unknownCommandMessage is the parsed UnknownCommandMessage, if set.
+	unknownCommandMessage *template.Template
+	// pipeline holds the middlewares named by ResponsePipeline.
+	pipeline []Middleware
+	// reactionRoles holds the parsed ReactionRoles.
+	reactionRoles map[reactionRoleKey]string
+	// buttons holds the commands' buttons by custom ID.
+	buttons map[string]Button
+	// bannedWords matches any of BannedWords, if set.
+	bannedWords *regexp.Regexp
+	// whitelist holds the IDs in Whitelist, for fast lookups.
+	whitelist map[string]struct{}
+	// blacklist holds the IDs in Blacklist, for fast lookups.
+	blacklist map[string]struct{}
+	// contains holds the commands matched by substring, in the order they
+	// are tried.
+	contains []*Command
+	// patterns holds the commands matched by pattern, in the order they are
+	// tried.
+	patterns []*Command
+}
+
+// cooldown returns the minimum interval between a user's uses of a command.
+func (c *Config) cooldown() time.Duration {
+	return time.Duration(c.Cooldown) * time.Second
+}
+
+// userCooldown returns the minimum interval between uses of a command by a
+// user with the given roles: the shortest cooldown of any of their roles with
+// an override, or the default cooldown if none have one.
+func (c *Config) userCooldown(roles []string) time.Duration {
+	cooldown, overridden := 0, false
+	for _, role := range roles {
+		if seconds, ok := c.RoleCooldowns[role]; ok && (!overridden || seconds < cooldown) {
+			cooldown, overridden = seconds, true
+		}
+	}
+	if !overridden {
+		return c.cooldown()
+	}
+	return time.Duration(cooldown) * time.Second
+}
+
+// maxUserCooldown returns the longest minimum interval between uses of a
+// command by any user.
+func (c *Config) maxUserCooldown() time.Duration {
+	max := c.cooldown()
+	for _, seconds := range c.RoleCooldowns {
+		if cooldown := time.Duration(seconds) * time.Second; cooldown > max {
+			max = cooldown
+		}
+	}
+	return max
+}
+
+// commandCooldown returns the default minimum interval between uses of a
+// command within its cooldown scope.
+func (c *Config) commandCooldown() time.Duration {
+	return time.Duration(c.CommandCooldown) * time.Second
+}
+
+// maxCommandCooldown returns the longest minimum interval between uses of any
+// command within its cooldown scope.
+func (c *Config) maxCommandCooldown() time.Duration {
+	max := c.commandCooldown()
+	for _, cmd := range c.lookup {
+		if cmd.Cooldown > max {
+			max = cmd.Cooldown
+		}
+	}
+	for _, cmd := range c.contains {
+		if cmd.Cooldown > max {
+			max = cmd.Cooldown
+		}
+	}
+	for _, cmd := range c.patterns {
+		if cmd.Cooldown > max {
+			max = cmd.Cooldown
+		}
+	}
+	return max
+}
+
+// statsResetInterval returns how often command stats are reset.
+func (c *Config) statsResetInterval() time.Duration {
+	return time.Duration(c.StatsReset) * time.Second
+}
+
+// requestTimeout returns the longest a call to the Discord API may take.
+func (c *Config) requestTimeout() time.Duration {
+	return time.Duration(c.RequestTimeout) * time.Second
+}
+
+// typingMaxDelay returns the longest the bot will appear to type.
+func (c *Config) typingMaxDelay() time.Duration {
+	return time.Duration(c.TypingMaxDelay) * time.Second
 }
 
 func loadConfig() {
-	var config Config
+	// Set defaults for options that are enabled unless turned off.
+	config := Config{
+		IgnoreBots:        true,
+		ConnectRetries:    defaultConnectRetries,
+		ConnectRetryDelay: defaultConnectRetryDelay,
+		RequestTimeout:    defaultRequestTimeout,
+	}
+
+	// Only load one config at a time.
+	ConfigMu.Lock()
+	defer ConfigMu.Unlock()
 
 	// Open config file.
-	file, err := ioutil.ReadFile("config.yaml")
+	file, err := ioutil.ReadFile(ConfigPath)
 	if err != nil {
 		if !ConfigLoaded {
 			// If no config has been loaded previously, exit.
-			log.Fatal(err)
+			fatal("error reading config", "err", err)
 		} else {
 			// If a config has been loaded previously, do nothing.
-			log.Println(err)
+			slog.Error("error reading config", "err", err)
 			return
 		}
 	}
 
 	// Unmarshal config file.
-	err = yaml.UnmarshalStrict(file, &config)
+	err = decodeConfig(ConfigPath, file, &config)
+	if err != nil {
+		if !ConfigLoaded {
+			// If no config has been loaded previously, exit.
+			fatal("error parsing config", "err", err)
+		} else {
+			// If a config has been loaded previously, do nothing.
+			slog.Error("error parsing config", "err", err)
+			return
+		}
+	}
+
+	// Merge in the commands from included files.
+	err = mergeIncludes(&config, ConfigPath)
 	if err != nil {
 		if !ConfigLoaded {
 			// If no config has been loaded previously, exit.
-			log.Fatal(err)
+			fatal("error including config", "err", err)
+		} else {
+			// If a config has been loaded previously, do nothing.
+			slog.Error("error including config", "err", err)
+			return
+		}
+	}
+
+	// Upgrade the config from older versions of its format.
+	migrateConfig(&config)
+
+	// Expand environment variables, if enabled. Commands read from the
+	// database are added by admins at runtime and are never expanded, so that
+	// they can't be used to reveal the environment.
+	if config.ExpandEnv {
+		err = expandConfigEnv(&config, os.LookupEnv)
+		if err != nil {
+			if !ConfigLoaded {
+				// If no config has been loaded previously, exit.
+				fatal("error expanding environment variables", "err", err)
+			} else {
+				// If a config has been loaded previously, do nothing.
+				slog.Error("error expanding environment variables", "err", err)
+				return
+			}
+		}
+	}
+
+	// Read commands from the database, if enabled.
+	if DB != nil {
+		config.Commands, err = loadCommands(DB)
+		if err != nil {
+			if !ConfigLoaded {
+				// If no config has been loaded previously, exit.
+				fatal("error reading commands from database", "err", err)
+			} else {
+				// If a config has been loaded previously, do nothing.
+				slog.Error("error reading commands from database", "err", err)
+				return
+			}
+		}
+	}
+
+	// Fetch commands from the HTTP endpoint, if enabled. If fetching fails
+	// on reload, the commands last fetched are kept.
+	if Remote != nil {
+		ctx, cancel := requestContext(&config)
+		var commands map[string]CommandConfig
+		commands, err = Remote.load(ctx)
+		cancel()
+		if err != nil {
+			if commands == nil {
+				// If no commands have been fetched previously, exit.
+				fatal("error fetching commands", "url", Remote.url, "err", err)
+			}
+			slog.Error("error fetching commands; keeping previous commands", "url", Remote.url, "err", err)
+		}
+		config.Commands = commands
+	}
+
+	// Validate config, reporting all problems at once. Shard settings from
+	// the environment override those in the file.
+	var errs []error
+	if err := applyShardEnv(&config, os.Getenv); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validate(config)...)
+	for _, err := range errs {
+		slog.Error("invalid config", "err", err)
+	}
+	if len(errs) > 0 {
+		if !ConfigLoaded {
+			// If no config has been loaded previously, exit.
+			fatal("config is invalid", "errors", len(errs))
 		} else {
 			// If a config has been loaded previously, do nothing.
-			log.Println(err)
+			slog.Error("config is invalid; keeping previous config", "errors", len(errs))
 			return
 		}
 	}
 
-	// Export values to global variables.
-	Commands = config.Commands
-	WhitelistEnabled= config.WhitelistEnabled
-	Whitelist = config.Whitelist
+	// Build the command lookups, then swap in the new config all at once.
+	buildCommands(&config)
+	if config.Webhook != "" {
+		// The URL has already been validated.
+		config.webhook, _ = parseWebhookURL(config.Webhook)
+	}
+	if config.CooldownMessage != "" {
+		config.cooldownMessage, err = template.New("cooldown").Parse(config.CooldownMessage)
+		if err != nil {
+			slog.Warn("invalid cooldown message; ignoring it", "err", err)
+		}
+	}
+	if config.WelcomeMessage != "" {
+		config.welcomeMessage, err = template.New("welcome").Parse(config.WelcomeMessage)
+		if err != nil {
+			slog.Warn("invalid welcome message; ignoring it", "err", err)
+		}
+	}
+	if config.OnlineMessage != "" {
+		config.onlineMessage, err = template.New("online").Parse(config.OnlineMessage)
+		if err != nil {
+			slog.Warn("invalid online message; ignoring it", "err", err)
+		}
+	}
+	if config.UnknownCommandMessage != "" {
+		config.unknownCommandMessage, err = template.New("unknown").Parse(config.UnknownCommandMessage)
+		if err != nil {
+			slog.Warn("invalid unknown command message; ignoring it", "err", err)
+		}
+	}
+	config.pipeline = resolvePipeline(config.ResponsePipeline)
+	config.reactionRoles = buildReactionRoles(config.ReactionRoles)
+	config.bannedWords = bannedWordsPattern(config.BannedWords)
+	config.whitelist = idSet(config.Whitelist)
+	config.blacklist = idSet(config.Blacklist)
+	previous := CurrentConfig.Swap(&config)
+	setRateLimit(Limiter, config.MessagesPerSecond)
+	if Scheduler != nil {
+		Scheduler.start(config.Schedules, scheduleLocation(config.Timezone))
+	}
+	if PresenceRotator != nil {
+		PresenceRotator.start(config.Presence, config.presenceInterval())
+	}
 
 	// Success!
+	if ConfigLoaded {
+		Reloads.Add(1)
+		logConfigDiff(previous, &config)
+	}
 	ConfigLoaded = true
-	log.Println("config loaded successfully")
+	slog.Info("config loaded successfully")
+}
+
+// parseFlags sets ConfigPath, ValidateOnly and TestMessage from the command
+// line args. The config path defaults to CONFIG_PATH, read with getenv, and
+// then to config.yaml.
+func parseFlags(args []string, getenv func(string) string) error {
+	ConfigPath = getenv("CONFIG_PATH")
+	if ConfigPath == "" {
+		ConfigPath = "config.yaml"
+	}
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.StringVar(&ConfigPath, "config", ConfigPath, "path of the config file")
+	fs.BoolVar(&ValidateOnly, "validate", false, "validate the config and exit")
+	fs.StringVar(&TestMessage, "test", "", "print the response to a message and exit")
+	return fs.Parse(args)
 }
 
-func init() {
-	// Get API token from environment.
+// setup reads the environment and command line, opens storage, and loads
+// the config. It is called from main rather than init, so that tests can
+// run without a config file.
+func setup() {
+	// Set up logging in the format and at the level given by the
+	// environment.
+	level, ok := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(newLogger(os.Stderr, os.Getenv("LOG_FORMAT"), level))
+	if !ok {
+		slog.Warn("unknown log level; using info", "level", os.Getenv("LOG_LEVEL"))
+	}
+	// Get API token from environment, or from a file if one is given.
 	Token = os.Getenv("TOKEN")
+	TokenFile = os.Getenv("TOKEN_FILE")
+	if TokenFile != "" {
+		var err error
+		Token, err = readTokenFile(TokenFile)
+		if err != nil {
+			fatal("error reading token file", "err", err)
+		}
+	}
+	// Fail over to backup tokens, if any are given.
+	Tokens = parseTokens(os.Getenv("TOKENS"))
+	if len(Tokens) == 0 {
+		Tokens = []string{Token}
+	}
+	Token = Tokens[0]
+	// Handle the bot's own messages, if enabled for testing.
+	if allow := os.Getenv("DEBUG_ALLOW_SELF"); allow != "" {
+		var err error
+		DebugAllowSelf, err = strconv.ParseBool(allow)
+		if err != nil {
+			fatal("invalid DEBUG_ALLOW_SELF", "value", allow, "err", err)
+		}
+		if DebugAllowSelf {
+			slog.Warn("handling the bot's own messages; this is meant only for testing")
+		}
+	}
+	// Get config path from environment, overridden by the command line.
+	switch err := parseFlags(os.Args[1:], os.Getenv); {
+	case err == flag.ErrHelp:
+		os.Exit(0)
+	case err != nil:
+		os.Exit(2)
+	}
+	// Open the command database, if enabled.
+	if os.Getenv("STORAGE") == "sqlite" {
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "commands.db"
+		}
+		var err error
+		DB, err = openStorage(path)
+		if err != nil {
+			fatal("error opening database", "err", err)
+		}
+	}
+	// Fetch commands from an HTTP endpoint, if enabled.
+	if os.Getenv("STORAGE") == "http" {
+		url := os.Getenv("COMMANDS_URL")
+		if url == "" {
+			fatal("COMMANDS_URL must be set when STORAGE is http")
+		}
+		Remote = newRemoteCommands(url)
+		if refresh := os.Getenv("COMMANDS_REFRESH"); refresh != "" {
+			seconds, err := strconv.Atoi(refresh)
+			if err != nil || seconds < 0 {
+				fatal("invalid COMMANDS_REFRESH; must be a number of seconds", "value", refresh)
+			}
+			RemoteRefresh = time.Duration(seconds) * time.Second
+		}
+	}
+	// Seed the random number generator used to pick responses.
+	rand.Seed(time.Now().UnixNano())
 	// Load config file.
 	loadConfig()
 }
 
 func main() {
+	setup()
+
+	// If testing a message, print the response and stop now.
+	if TestMessage != "" {
+		dryRun(os.Stdout, CurrentConfig.Load(), TestMessage)
+		return
+	}
+
+	// If only validating the config, stop now. Loading it has already exited
+	// with an error if it is invalid.
+	if ValidateOnly {
+		slog.Info("config is valid")
+		return
+	}
+
+	StartTime = time.Now()
+
 	// Create a new Discord session using the provided bot token.
 	dg, err := discordgo.New("Bot " + Token)
 	if err != nil {
-		log.Println("error creating discord session", err)
+		slog.Error("error creating discord session", "err", err)
 		return
 	}
 
 	// Register the messageCreate func as a callback for MessageCreate events.
 	dg.AddHandler(messageCreate)
+	// Register the messageUpdate func as a callback for MessageUpdate events.
+	dg.AddHandler(messageUpdate)
+	// Register the interactionCreate func as a callback for InteractionCreate
+	// events.
+	dg.AddHandler(interactionCreate)
+	// Register the guildMemberAdd func as a callback for GuildMemberAdd
+	// events.
+	dg.AddHandler(guildMemberAdd)
+	// Register the messageReactionAdd and messageReactionRemove funcs as
+	// callbacks for MessageReactionAdd and MessageReactionRemove events.
+	dg.AddHandler(messageReactionAdd)
+	dg.AddHandler(messageReactionRemove)
+	// Register the ready and resumed funcs as callbacks for Ready and Resumed
+	// events, to restore state and announce reconnects.
+	dg.AddHandler(ready)
+	dg.AddHandler(resumed)
+	// Register the connect and disconnect funcs as callbacks for Connect and
+	// Disconnect events, to track the health of the session.
+	dg.AddHandler(connect)
+	dg.AddHandler(disconnect)
+
+	// Serve health checks, if enabled.
+	var health *http.Server
+	if addr := os.Getenv("HEALTH_ADDR"); addr != "" {
+		health, err = startHealthServer(addr)
+		if err != nil {
+			slog.Error("error starting health server", "err", err)
+			return
+		}
+	}
+
+	// Ask for the configured intents, along with any needed by enabled
+	// features, such as DMs if they are allowed.
+	dg.Identify.Intents = resolveIntents(CurrentConfig.Load().Intents)
+	if CurrentConfig.Load().AllowDM {
+		dg.Identify.Intents |= discordgo.IntentsDirectMessages
+	}
+	// Member events are privileged, so only ask for them if welcome messages
+	// are enabled.
+	if CurrentConfig.Load().WelcomeChannel != "" {
+		dg.Identify.Intents |= discordgo.IntentsGuildMembers
+	}
+	// Reaction roles and confirmations need reaction events.
+	if len(CurrentConfig.Load().ReactionRoles) > 0 || CurrentConfig.Load().needsReactions() {
+		dg.Identify.Intents |= discordgo.IntentsGuildMessageReactions
+	}
+	if CurrentConfig.Load().AllowDM && CurrentConfig.Load().needsReactions() {
+		dg.Identify.Intents |= discordgo.IntentsDirectMessageReactions
+	}
+	// Guild locales are only known if guilds are tracked.
+	if len(CurrentConfig.Load().Locales) > 0 {
+		dg.Identify.Intents |= discordgo.IntentsGuilds
+	}
+
+	// Connect as one shard of a sharded bot, if enabled. Shard settings are
+	// only read at startup.
+	if config := CurrentConfig.Load(); config.ShardCount > 0 {
+		dg.ShardID = config.ShardID
+		dg.ShardCount = config.ShardCount
+	}
 
-	// In this example, we only care about receiving message events.
-	dg.Identify.Intents = discordgo.MakeIntent(discordgo.IntentsGuildMessages)
+	// Send responses from a pool of workers, if enabled. Queue settings are
+	// only read at startup.
+	config := CurrentConfig.Load()
+	if config.SendWorkers > 0 {
+		SendQueue = newSendQueue(config.SendQueueSize, config.SendQueuePolicy)
+		SendQueue.start(config.SendWorkers)
+	}
 
-	// Open a websocket connection to Discord and begin listening.
-	err = dg.Open()
+	// Open a websocket connection to Discord and begin listening, retrying
+	// in case of a transient failure, and then trying any backup tokens.
+	index, err := openWithFailover(Tokens, func(token string) error {
+		setToken(dg, token)
+		return openWithRetry(dg.Open, config.ConnectRetries, config.connectRetryDelay(), time.Sleep)
+	})
 	if err != nil {
-		log.Println("error opening connection", err)
+		slog.Error("giving up opening connection", "attempts", config.ConnectRetries+1, "tokens", len(Tokens))
 		return
 	}
+	Token = Tokens[index]
+	slog.Info("connected", "shard_id", dg.ShardID, "shard_count", dg.ShardCount, "token_index", index)
+	Ready.Store(true)
+
+	// Announce that the bot is online, if enabled.
+	announceOnline(dg, CurrentConfig.Load(), false)
+
+	// Register slash commands, if enabled.
+	if CurrentConfig.Load().SlashCommands {
+		err = registerSlashCommands(dg, CurrentConfig.Load())
+		if err != nil {
+			slog.Error("error registering slash commands", "err", err)
+		}
+	}
+
+	// Send scheduled messages.
+	Scheduler = newScheduler(func(channelID, message string) {
+		sendScheduled(dg, channelID, message)
+	})
+	Scheduler.start(CurrentConfig.Load().Schedules, scheduleLocation(CurrentConfig.Load().Timezone))
+
+	// Show the bot's status.
+	PresenceRotator = newPresenceRotator(dg.UpdateStatusComplex)
+	PresenceRotator.start(CurrentConfig.Load().Presence, CurrentConfig.Load().presenceInterval())
+
+	// Background tasks run until ctx is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	// Remove expired cooldowns in the background.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pruneCooldowns(ctx)
+	}()
+
+	// Refresh commands from the HTTP endpoint in the background, if enabled.
+	if Remote != nil && RemoteRefresh > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			refreshCommands(ctx, Remote, RemoteRefresh, loadConfig)
+		}()
+	}
+
+	// Forget old responded-to messages in the background.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pruneResponded(ctx)
+	}()
+
+	// Reset command stats in the background, if enabled.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resetStats(ctx)
+	}()
 
 	// Wait here until CTRL-C or other term signal is received.
-	log.Println("running; press ctrl-c to exit")
+	slog.Info("running; press ctrl-c to exit")
 
 	rc := make(chan os.Signal, 1)
 	signal.Notify(rc, syscall.SIGHUP)
-	// Reload config on SIGHUP.
+	// Reload config, and the token if it is read from a file, on SIGHUP.
+	wg.Add(1)
 	go func() {
-		for range rc {
+		defer wg.Done()
+		reloadOnSignal(ctx, rc, func() {
 			loadConfig()
-		}
+			if TokenFile != "" {
+				reloadToken(dg, TokenFile)
+			}
+		})
 	}()
 
+	// Reload config when the file changes, if enabled.
+	var watcher *fsnotify.Watcher
+	if CurrentConfig.Load().WatchConfig {
+		watcher, err = watchConfig(ConfigPath, loadConfig)
+		if err != nil {
+			slog.Error("error watching config", "err", err)
+		}
+	}
+
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
-	// Cleanly close down the Discord session.
-	log.Println("exiting...")
+	slog.Info("exiting...")
+	Ready.Store(false)
+	// Stop background tasks.
+	signal.Stop(rc)
+	cancel()
+	wg.Wait()
+	// Cleanly close down the config watcher and Discord session.
+	if watcher != nil {
+		err = watcher.Close()
+		if err != nil {
+			slog.Error("error closing config watcher", "err", err)
+		}
+	}
+	Scheduler.stop()
+	PresenceRotator.stop()
+	SendQueue.stop()
+	stopHealthServer(health)
 	err = dg.Close()
 	if err != nil {
-		log.Fatal(err)
+		fatal("error closing discord session", "err", err)
+	}
+	if DB != nil {
+		err = DB.Close()
+		if err != nil {
+			fatal("error closing database", "err", err)
+		}
 	}
 }
 
 func messageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	var approved bool
+	h := &MessageHandler{Session: s, BotID: s.State.User.ID, AllowSelf: DebugAllowSelf, GuildLocale: stateGuildLocale(s.State)}
+	h.Handle(m)
+}
+
+// Handle responds to the message m, if it is a command.
+func (h *MessageHandler) Handle(m *discordgo.MessageCreate) {
+	config := CurrentConfig.Load()
+
+	// Ignore all messages created by the bot itself, unless allowed.
+	self := m.Author.ID == h.BotID
+	if self && !h.AllowSelf {
+		return
+	}
+
+	// Ignore all messages created by other bots, if enabled.
+	if config.IgnoreBots && m.Author.Bot && !self {
+		return
+	}
+
+	// Warn if messages can't be read.
+	checkContent(m.Message)
+
+	// Ignore all DMs, unless they are allowed.
+	if m.GuildID == "" && !config.AllowDM {
+		return
+	}
+
+	// Ignore all messages created by blacklisted users.
+	if isBlacklisted(config, m.Author.ID) {
+		slog.Debug("ignoring message from blacklisted user", "author_id", m.Author.ID)
+		return
+	}
+
+	// Ignore all messages containing banned words.
+	if containsBannedWord(config, m.Content) {
+		slog.Debug("ignoring message containing a banned word", "author_id", m.Author.ID, "channel_id", m.ChannelID)
+		return
+	}
+
+	// Strip the bot's mention from the message, if required.
+	content := m.Content
+	var mentioned bool
+	if config.RequireMention {
+		content, mentioned = stripMention(content, h.BotID, m.Mentions)
+	}
+
+	// Strip the prefix from the message, if any.
+	name, prefix, hasPrefix := parseCommand(config, content, mentioned)
+
+	// Handle admin commands, if the author is an admin.
+	if hasPrefix && isAdmin(config, m.Author.ID) && handleAdmin(h.Session, m, config, name) {
+		return
+	}
+
+	// Normalize the command name, if needed. Arguments keep their case.
+	raw := name
+	if config.CaseInsensitive {
+		name = strings.ToLower(name)
+	}
+
+	// Check if the message is a command, trying exact matches before
+	// commands with arguments, substrings, and patterns.
+	var cmd *Command
+	var args string
+	if hasPrefix {
+		cmd = config.lookup[name]
+	}
+	if cmd == nil && hasPrefix {
+		cmd, args = findWithArgs(config, raw)
+	}
+	if cmd == nil && (hasPrefix || !config.RequireMention) {
+		cmd = findContains(config, content)
+	}
+	if cmd == nil && (hasPrefix || !config.RequireMention) {
+		cmd = findPattern(config, content)
+	}
+
+	// If the message can't be a command, do nothing.
+	if cmd == nil && !hasPrefix {
+		return
+	}
+
+	// If the author is not approved, do nothing.
+	if !isApproved(h.Session, config, m.GuildID, m.Author, m.Member) {
+		return
+	}
+
+	// If the author's account or membership is too new, do nothing.
+	if !meetsAgeRequirements(h.Session, config, m.GuildID, m.Author, m.Member, time.Now()) {
+		return
+	}
+
+	// Respond to built-in commands, or suggest a command if enabled, or say
+	// that the command is unknown if enabled. All of these are sent in the
+	// channel the message was sent in.
+	if cmd == nil {
+		if !canSendIn(config, m.ChannelID) {
+			slog.Info("channel is not in allowed_channels; skipping response", "channel_id", m.ChannelID)
+			return
+		}
+		if handleBuiltin(h.Session, m, config, prefix, name) {
+			markResponded(config, m)
+			return
+		}
+		// Without a prefix or mention, every message would be an unknown
+		// command.
+		if !config.Prefix.set() && !mentioned {
+			return
+		}
+		if config.SuggestCommands && sendSuggestion(h.Session, m, config, prefix, name) {
+			return
+		}
+		sendUnknownCommand(h.Session, m, config, prefix, name)
+		return
+	}
+
+	// If the command may not be used in this channel, do nothing.
+	if !cmd.allowedIn(m.ChannelID) {
+		return
+	}
+
+	// If the command is restricted to other users or roles, do nothing.
+	if !cmd.allowedFor(h.Session, m.GuildID, m.Author, m.Member) {
+		return
+	}
+
+	// If the author lacks the command's required permission, do nothing.
+	if !cmd.permittedFor(h.Session, m.GuildID, m.Author.ID, m.ChannelID) {
+		return
+	}
+
+	// If the bot may not send in the channel it would respond in, do
+	// nothing.
+	if !canSendIn(config, cmd.channelFor(m.ChannelID)) {
+		slog.Info("channel is not in allowed_channels; skipping response", "command", cmd.Name, "channel_id", cmd.channelFor(m.ChannelID))
+		return
+	}
+
+	// If the command is on cooldown within its scope, do nothing.
+	if !commandReady(config, cmd, m.Author.ID, m.ChannelID) {
+		return
+	}
+
+	// The message is a usable command, so don't respond to it again if it is
+	// edited.
+	markResponded(config, m)
+
+	// If the author is on cooldown, tell them so if enabled.
+	data := messageData(m)
+	data.ArgString = args
+	data.Args = strings.Fields(args)
+	if h.GuildLocale != nil && m.GuildID != "" {
+		data.Locale = h.GuildLocale(m.GuildID)
+	}
+	if remaining, ok := checkCooldown(config, m.Author.ID, memberRoles(m.Member), cmd.Name); !ok {
+		sendCooldownNotice(h.Session, m, config, cmd.Name, remaining, data)
+		return
+	}
 
-	// Ignore all messages created by the bot itself.
-	if m.Author.ID == s.State.User.ID {
+	// If the author has used up their quota, do nothing. Quota is only
+	// checked once the author is off cooldown, so that uses blocked by
+	// the cooldown don't count against it.
+	if !checkQuota(h.Session, config, m.GuildID, m.Author.ID) {
+		slog.Debug("ignoring command from user over quota", "author_id", m.Author.ID, "guild_id", m.GuildID)
 		return
 	}
 
-	// Determine if the author is approved to use the bot.
-	if WhitelistEnabled {
-		for _, id := range Whitelist {
-			if id == m.Author.ID {
-				approved = true
-				break
+	// Start the command's cooldown within its scope.
+	if !startCommandCooldown(config, cmd, m.Author.ID, m.ChannelID) {
+		return
+	}
+
+	// React to the message, if the command has a reaction.
+	if cmd.Reaction != "" {
+		ctx, cancel := requestContext(config)
+		err := h.Session.MessageReactionAdd(m.ChannelID, m.ID, cmd.Reaction, discordgo.WithContext(ctx))
+		cancel()
+		if err != nil {
+			slog.Error("error adding reaction", "command", cmd.Name, "err", err)
+		}
+	}
+
+	// Respond to the message, if the command has responses.
+	if cmd.hasResponse() {
+		// Render the command's responses.
+		vals, err := cmd.respond(data)
+		if err != nil {
+			slog.Error("error rendering response", "command", cmd.Name, "err", err)
+			return
+		}
+
+		// Transform the responses, if enabled.
+		for _, val := range vals {
+			applyPipeline(context.Background(), config.pipeline, val)
+		}
+
+		// Wait for the author to confirm the command in the background if
+		// needed, or queue the responses to be sent by a worker if enabled,
+		// and otherwise send a sequence in the background, so that its
+		// delays don't hold up other messages.
+		switch {
+		case cmd.Confirm != "" && !h.Synchronous:
+			go h.confirmThenRespond(m, config, cmd, data, vals)
+		case SendQueue != nil && !h.Synchronous:
+			if !SendQueue.enqueue(func() { h.respond(m, config, cmd, data, vals) }) {
+				return
+			}
+		case cmd.Sequence && !h.Synchronous:
+			go h.respond(m, config, cmd, data, vals)
+		default:
+			if !h.respond(m, config, cmd, data, vals) {
+				return
 			}
 		}
-	} else {
-		approved = true
 	}
+	Stats.record(cmd.Name)
+	cmd.logHandled(m.Author.ID, m.ChannelID)
+	audit(h.Session, config, m.Author, cmd.Name, m.ChannelID)
+	emitEvent(config, m.Author, cmd.Name, m.GuildID, m.ChannelID)
+}
 
-	// If the author is not approved, do nothing.
-	if !approved {
+// respond sends the responses to the command used in m, in order, and
+// reports whether they were all sent.
+func (h *MessageHandler) respond(m *discordgo.MessageCreate, config *Config, cmd *Command, data TemplateData, vals []*discordgo.MessageSend) bool {
+	// Start a thread to respond in, if enabled.
+	channelID := cmd.channelFor(m.ChannelID)
+	var threadID string
+	if cmd.Thread {
+		threadID = startThread(h.Session, m, config, cmd.Name)
+		if threadID != "" {
+			channelID = threadID
+		}
+	}
+
+	for i, val := range vals {
+		if i > 0 {
+			time.Sleep(cmd.SequenceDelay)
+		}
+
+		// Appear to type the response, if enabled.
+		if config.TypingIndicator {
+			simulateTyping(h.Session, channelID, val, config.typingMaxDelay())
+		}
+
+		// Send a message corresponding to the given command, through a
+		// webhook, in a thread, or to another channel if one is configured.
+		var err error
+		switch {
+		case cmd.Webhook != nil:
+			err = sendWebhook(h.Session, cmd.Webhook, val)
+		case cmd.TargetChannel != "":
+			err = sendResponseTo(h.Session, m, cmd.TargetChannel, config, val)
+		case threadID != "":
+			err = sendResponseTo(h.Session, m, threadID, config, val)
+		case config.webhook != nil:
+			err = sendWebhook(h.Session, config.webhook, val)
+		default:
+			err = sendResponse(h.Session, m, config, val)
+		}
+		if err != nil {
+			slog.Error("error sending response", "command", cmd.Name, "err", err)
+			return false
+		}
+	}
+
+	// Acknowledge the command where it was used, if it was responded to
+	// elsewhere.
+	if cmd.TargetChannel != "" && cmd.Acknowledge != nil && canSendIn(config, m.ChannelID) {
+		sendAcknowledgement(h.Session, m, config, cmd, data)
+	}
+
+	// Delete the command message now that it has been responded to, if
+	// enabled.
+	if config.DeleteTrigger {
+		ctx, cancel := requestContext(config)
+		err := h.Session.ChannelMessageDelete(m.ChannelID, m.ID, discordgo.WithContext(ctx))
+		cancel()
+		if err != nil {
+			slog.Warn("error deleting command message", "command", cmd.Name, "err", err)
+		}
+	}
+	return true
+}
+
+// confirmThenRespond asks the author of m to confirm the command, and sends
+// the responses if they do.
+func (h *MessageHandler) confirmThenRespond(m *discordgo.MessageCreate, config *Config, cmd *Command, data TemplateData, vals []*discordgo.MessageSend) {
+	if !canSendIn(config, m.ChannelID) {
+		slog.Info("channel is not in allowed_channels; skipping confirmation", "command", cmd.Name, "channel_id", m.ChannelID)
+		return
+	}
+	ch, err := confirm(h.Session, config, m.ChannelID, m.Author.ID, cmd.Confirm, cmd.confirmTimeout())
+	if err != nil {
+		slog.Error("error asking for confirmation", "command", cmd.Name, "err", err)
+		return
+	}
+	switch ch {
+	case choiceConfirm:
+		slog.Debug("command confirmed", "command", cmd.Name, "author_id", m.Author.ID)
+		h.respond(m, config, cmd, data, vals)
+	case choiceCancel:
+		slog.Debug("command cancelled", "command", cmd.Name, "author_id", m.Author.ID)
+	default:
+		slog.Debug("command confirmation timed out", "command", cmd.Name, "author_id", m.Author.ID)
+	}
+}
+
+// markResponded records that m has been responded to, if edits are handled.
+func markResponded(config *Config, m *discordgo.MessageCreate) {
+	if config.HandleEdits {
+		Responded.mark(m.ID, time.Now())
+	}
+}
+
+// messageData returns the template data for a response to m.
+func messageData(m *discordgo.MessageCreate) TemplateData {
+	return TemplateData{
+		User:      m.Author.Username,
+		Mention:   m.Author.Mention(),
+		ChannelID: m.ChannelID,
+		GuildID:   m.GuildID,
+	}
+}
+
+// sendCooldownNotice tells the author of m they are on cooldown for the
+// command, if enabled and they haven't been told already.
+// The notice is sent to the author's DMs instead, if enabled.
+func sendCooldownNotice(s Messenger, m *discordgo.MessageCreate, config *Config, command string, remaining time.Duration, data TemplateData) {
+	channelID := m.ChannelID
+	if config.CooldownDM {
+		var ok bool
+		channelID, ok = dmChannel(s, config, m.Author.ID)
+		if !ok {
+			return
+		}
+	}
+	if !canSendIn(config, channelID) {
+		return
+	}
+
+	text, ok := cooldownNotice(config, m.Author.ID, command, remaining, data)
+	if !ok {
 		return
 	}
+	err := sendResponseTo(s, m, channelID, config, &discordgo.MessageSend{Content: text})
+	if isDMClosed(err) {
+		slog.Warn("user has DMs closed; skipping cooldown message", "command", command, "author_id", m.Author.ID)
+	} else if err != nil {
+		slog.Error("error sending cooldown message", "command", command, "err", err)
+	}
+}
 
-	// Check if the message is a command.
-	val, isCmd := Commands[m.Content]
-	if isCmd {
-		// Send a message corresponding to the given command.
-		_, err := s.ChannelMessageSend(m.ChannelID, val)
+// dmChannel returns the ID of the DM channel with the user, opening it if
+// needed. If it can't be opened, the error is logged and false is returned.
+func dmChannel(s Messenger, config *Config, userID string) (string, bool) {
+	ctx, cancel := requestContext(config)
+	defer cancel()
+
+	channel, err := s.UserChannelCreate(userID, discordgo.WithContext(ctx))
+	if err != nil {
+		slog.Warn("error opening DM channel", "user_id", userID, "err", err)
+		return "", false
+	}
+	return channel.ID, true
+}
+
+// isDMClosed reports whether err is Discord refusing to send a message
+// because the user doesn't accept DMs from the bot.
+func isDMClosed(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeCannotSendMessagesToThisUser
+}
+
+// sendAcknowledgement tells the author of m that the command was responded
+// to in its target channel.
+func sendAcknowledgement(s Messenger, m *discordgo.MessageCreate, config *Config, cmd *Command, data TemplateData) {
+	text, err := render(cmd.Acknowledge, data)
+	if err != nil {
+		slog.Error("error rendering acknowledgement", "command", cmd.Name, "err", err)
+		return
+	}
+	err = sendResponse(s, m, config, &discordgo.MessageSend{Content: text})
+	if err != nil {
+		slog.Error("error sending acknowledgement", "command", cmd.Name, "err", err)
+	}
+}
+
+// sendUnknownCommand tells the author of m that the command with the given
+// name, used with the given prefix, doesn't exist, if enabled.
+func sendUnknownCommand(s Messenger, m *discordgo.MessageCreate, config *Config, prefix, name string) {
+	// A prefix or mention alone isn't an attempt at a command.
+	if config.unknownCommandMessage == nil || name == "" {
+		return
+	}
+	data := messageData(m)
+	data.Command = name
+	data.Prefix = prefix
+	text, err := render(config.unknownCommandMessage, data)
+	if err != nil {
+		slog.Error("error rendering unknown command message", "command", name, "err", err)
+		return
+	}
+	err = sendResponse(s, m, config, &discordgo.MessageSend{Content: text})
+	if err != nil {
+		slog.Error("error sending unknown command message", "command", name, "err", err)
+	}
+}
+
+// isApproved determines if the author is approved to use the bot. The
+// author's member info is fetched if member is nil and it is needed.
+func isApproved(s Messenger, config *Config, guildID string, author *discordgo.User, member *discordgo.Member) bool {
+	if !config.WhitelistEnabled {
+		return true
+	}
+
+	// Check if the author is whitelisted.
+	if _, ok := config.whitelist[author.ID]; ok {
+		return true
+	}
+
+	// Role-based approval is only possible in guilds.
+	if len(config.WhitelistRoles) == 0 || guildID == "" {
+		return false
+	}
+
+	// Fetch the author's member info if it wasn't included in the event.
+	if member == nil {
+		var err error
+		member, err = s.GuildMember(guildID, author.ID)
 		if err != nil {
-			log.Println(err)
+			slog.Error("error fetching guild member", "err", err)
+			return false
+		}
+	}
+
+	// Check if the author has a whitelisted role.
+	return hasRole(member, config.WhitelistRoles)
+}
+
+// canSendIn reports whether the bot may send messages in the given channel.
+// If AllowedChannels is set, it may only send in those channels.
+func canSendIn(config *Config, channelID string) bool {
+	if len(config.AllowedChannels) == 0 {
+		return true
+	}
+	for _, id := range config.AllowedChannels {
+		if id == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// isBlacklisted reports whether the user is blacklisted.
+func isBlacklisted(config *Config, userID string) bool {
+	_, ok := config.blacklist[userID]
+	return ok
+}
+
+// idSet returns the set of the given IDs. Lists of users can be large, so
+// they are checked against sets rather than scanned.
+func idSet(ids []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// hasRole reports whether member has any of the given roles.
+func hasRole(member *discordgo.Member, roles []string) bool {
+	for _, have := range member.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
 		}
 	}
+	return false
+}
+
+// buildCommands builds everything derived from the config's commands. It is
+// called whenever the commands change, so that nothing derived from them
+// goes stale.
+func buildCommands(config *Config) {
+	buildLookup(config)
+	config.buttons = buildButtons(config.Commands)
+}
+
+// buildLookup builds the lookups used to match the config's commands: the
+// map of exact command names and aliases, and the commands matched by
+// substring or pattern, in name order. Each command's responses are parsed,
+// and commands that fail to parse are logged and skipped. If CaseInsensitive
+// is set, keys are lowercased and other matches ignore case. Keys that
+// conflict with another are reported and ignored; command names take
+// precedence over aliases.
+func buildLookup(config *Config) {
+	// Sort keys so conflicts are resolved the same way on every load.
+	keys := make([]string, 0, len(config.Commands))
+	for k := range config.Commands {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lookup := make(map[string]*Command, len(config.Commands))
+	owners := make(map[string]string, len(config.Commands))
+	add := func(key, owner string, cmd *Command) {
+		if config.CaseInsensitive {
+			key = strings.ToLower(key)
+		}
+		if existing, exists := owners[key]; exists {
+			slog.Warn("command or alias conflicts with another; ignoring it", "command", owner, "key", key, "conflict", existing)
+			return
+		}
+		owners[key] = owner
+		lookup[key] = cmd
+	}
+
+	// Add commands before aliases so that aliases never shadow commands.
+	var contains, patterns []*Command
+	parsed := make([]*Command, len(keys))
+	for i, k := range keys {
+		if !config.Commands[k].enabled() {
+			slog.Debug("command is disabled; skipping it", "command", k)
+			continue
+		}
+		cmd, err := newCommand(k, config.Commands[k], config.CaseInsensitive)
+		if err == nil {
+			err = cmd.addLocales(config.Locales)
+		}
+		if err != nil {
+			slog.Warn("invalid command; skipping it", "command", k, "err", err)
+			continue
+		}
+		parsed[i] = cmd
+		switch {
+		case cmd.Pattern != nil:
+			patterns = append(patterns, cmd)
+		case cmd.Contains != "":
+			contains = append(contains, cmd)
+		default:
+			add(k, k, cmd)
+		}
+	}
+	for i, k := range keys {
+		if parsed[i] == nil {
+			continue
+		}
+		for _, alias := range config.Commands[k].Aliases {
+			add(alias, k, parsed[i])
+		}
+	}
+
+	config.lookup = lookup
+	config.contains = contains
+	config.patterns = patterns
+}
+
+// findContains returns the first command whose trigger appears in content,
+// or nil if there is none.
+func findContains(config *Config, content string) *Command {
+	if config.CaseInsensitive {
+		content = strings.ToLower(content)
+	}
+	for _, cmd := range config.contains {
+		if strings.Contains(content, cmd.Contains) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// findPattern returns the first command whose pattern matches content, or
+// nil if there is none.
+func findPattern(config *Config, content string) *Command {
+	for _, cmd := range config.patterns {
+		if cmd.Pattern.MatchString(content) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// findWithArgs returns the command that takes arguments named by the first
+// word of name, along with the rest of name as its arguments, if there is
+// one.
+func findWithArgs(config *Config, name string) (*Command, string) {
+	first, rest := splitWord(name)
+	if rest == "" {
+		return nil, ""
+	}
+	if config.CaseInsensitive {
+		first = strings.ToLower(first)
+	}
+	cmd := config.lookup[first]
+	if cmd == nil || !cmd.Args {
+		return nil, ""
+	}
+	return cmd, rest
+}
+
+// parseCommand strips the first of the configured prefixes that content
+// starts with, regardless of case if CaseInsensitive is set, and returns the
+// command name and the prefix as configured. It returns false if content is
+// not a candidate command. If a mention is required, content is a candidate
+// if it mentioned the bot or, if a prefix is set, starts with a prefix.
+func parseCommand(config *Config, content string, mentioned bool) (string, string, bool) {
+	// A mention stands in for the prefix, but a prefix may still be used.
+	if config.RequireMention && mentioned {
+		prefix, name, ok := config.Prefix.match(content, config.CaseInsensitive)
+		if !ok {
+			prefix, name = config.Prefix.primary(), content
+		}
+		return name, prefix, name != ""
+	}
+	if config.RequireMention && !config.Prefix.set() {
+		return "", "", false
+	}
+
+	// Without a prefix, the whole message is the command.
+	if !config.Prefix.set() {
+		return content, "", true
+	}
+
+	// Ignore messages that don't start with a prefix.
+	prefix, name, ok := config.Prefix.match(content, config.CaseInsensitive)
+	if !ok {
+		return "", "", false
+	}
+
+	// Ignore messages that consist of only the prefix.
+	if name == "" {
+		return "", "", false
+	}
+
+	return name, prefix, true
+}
+
+// stripMention removes mentions of the bot from content and trims the
+// result. It reports whether the bot was among the mentioned users.
+func stripMention(content, botID string, mentions []*discordgo.User) (string, bool) {
+	var mentioned bool
+	for _, user := range mentions {
+		if user.ID == botID {
+			mentioned = true
+			break
+		}
+	}
+	if !mentioned {
+		return content, false
+	}
+
+	content = strings.ReplaceAll(content, "<@"+botID+">", "")
+	content = strings.ReplaceAll(content, "<@!"+botID+">", "")
+	return strings.TrimSpace(content), true
 }
@@ -0,0 +1,643 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseFlagsConfigPath(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{name: "default", want: "config.yaml"},
+		{name: "env", env: "env.yaml", want: "env.yaml"},
+		{name: "flag", args: []string{"-config", "flag.yaml"}, want: "flag.yaml"},
+		{name: "flag over env", env: "env.yaml", args: []string{"-config", "flag.yaml"}, want: "flag.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				if key == "CONFIG_PATH" {
+					return tt.env
+				}
+				return ""
+			}
+			if err := parseFlags(tt.args, getenv); err != nil {
+				t.Fatalf("parseFlags: %v", err)
+			}
+			if ConfigPath != tt.want {
+				t.Errorf("ConfigPath = %q, want %q", ConfigPath, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlagsModes(t *testing.T) {
+	getenv := func(string) string { return "" }
+	if err := parseFlags([]string{"-validate", "-test", "!ping"}, getenv); err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	if !ValidateOnly {
+		t.Error("ValidateOnly = false, want true")
+	}
+	if TestMessage != "!ping" {
+		t.Errorf("TestMessage = %q, want %q", TestMessage, "!ping")
+	}
+}
+
+func TestHandle(t *testing.T) {
+	const botID = "1"
+	tests := []struct {
+		name   string
+		config string
+		msg    *discordgo.MessageCreate
+		want   []string
+	}{
+		{
+			name:   "command",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "not a command",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "ping", false),
+		},
+		{
+			name:   "whitelisted",
+			config: "prefix: \"!\"\nwhitelist_enabled: true\nwhitelist: [\"30\"]\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "not whitelisted",
+			config: "prefix: \"!\"\nwhitelist_enabled: true\nwhitelist: [\"30\"]\ncommands:\n  ping: pong\n",
+			msg:    testMessage("31", "!ping", false),
+		},
+		{
+			name:   "blacklisted",
+			config: "prefix: \"!\"\nblacklist: [\"30\"]\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+		},
+		{
+			name:   "whitelisted and blacklisted",
+			config: "prefix: \"!\"\nwhitelist_enabled: true\nwhitelist: [\"30\"]\nblacklist: [\"30\"]\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+		},
+		{
+			name:   "empty blacklist",
+			config: "prefix: \"!\"\nwhitelist_enabled: true\nwhitelist: [\"30\"]\nblacklist: []\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "suggestion",
+			config: "prefix: \"!\"\nsuggest_commands: true\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!pnig", false),
+			want:   []string{"Did you mean `!ping`?"},
+		},
+		{
+			name:   "no suggestion without prefix",
+			config: "suggest_commands: true\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "pnig", false),
+		},
+		{
+			name:   "mention",
+			config: "require_mention: true\ncommands:\n  ping: pong\n",
+			msg:    mentioning(testMessage("30", "<@1> ping", false), botID),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "nickname mention",
+			config: "require_mention: true\ncommands:\n  ping: pong\n",
+			msg:    mentioning(testMessage("30", "<@!1>   ping", false), botID),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "no mention",
+			config: "require_mention: true\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "ping", false),
+		},
+		{
+			name:   "mention of someone else",
+			config: "require_mention: true\ncommands:\n  ping: pong\n",
+			msg:    mentioning(testMessage("30", "<@33> ping", false), "33"),
+		},
+		{
+			name:   "prefix instead of mention",
+			config: "prefix: \"!\"\nrequire_mention: true\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "mention instead of prefix",
+			config: "prefix: \"!\"\nrequire_mention: true\ncommands:\n  ping: pong\n",
+			msg:    mentioning(testMessage("30", "<@1> ping", false), botID),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "contains",
+			config: "commands:\n  coffee:\n    match: contains\n    output: Coffee!\n",
+			msg:    testMessage("30", "is there any coffee left?", false),
+			want:   []string{"Coffee!"},
+		},
+		{
+			name:   "exact before contains",
+			config: "commands:\n  coffee:\n    match: contains\n    output: Coffee!\n  coffee time: Break!\n",
+			msg:    testMessage("30", "coffee time", false),
+			want:   []string{"Break!"},
+		},
+		{
+			name:   "unknown command",
+			config: "prefix: \"!\"\nunknown_command_message: \"No {{.Prefix}}{{.Command}} here\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!dance", false),
+			want:   []string{"No !dance here"},
+		},
+		{
+			name:   "unknown command without prefix",
+			config: "prefix: \"!\"\nunknown_command_message: \"No {{.Prefix}}{{.Command}} here\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "dance", false),
+		},
+		{
+			name:   "prefix alone",
+			config: "prefix: \"!\"\nunknown_command_message: \"No {{.Prefix}}{{.Command}} here\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!", false),
+		},
+		{
+			name:   "unknown command mentioning the bot",
+			config: "require_mention: true\nunknown_command_message: \"No {{.Command}} here\"\ncommands:\n  ping: pong\n",
+			msg:    mentioning(testMessage("30", "<@1> dance", false), botID),
+			want:   []string{"No dance here"},
+		},
+		{
+			name:   "unknown command without prefix or mention",
+			config: "unknown_command_message: \"No {{.Command}} here\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "dance", false),
+		},
+		{
+			name:   "args",
+			config: "prefix: \"!\"\ncommands:\n  roll:\n    args: true\n    output: \"{{.User}} rolls {{index .Args 0}} ({{.ArgString}})\"\n  ping: pong\n",
+			msg:    testMessage("30", "!roll  d20   twice", false),
+			want:   []string{"user-30 rolls d20 (d20   twice)"},
+		},
+		{
+			name:   "args on a command without args",
+			config: "prefix: \"!\"\ncommands:\n  roll:\n    args: true\n    output: \"{{.User}} rolls {{index .Args 0}} ({{.ArgString}})\"\n  ping: pong\n",
+			msg:    testMessage("30", "!ping now", false),
+		},
+		{
+			name:   "exact match without args",
+			config: "prefix: \"!\"\ncommands:\n  roll:\n    args: true\n    output: \"{{.User}} rolls {{index .Args 0}} ({{.ArgString}})\"\n  ping: pong\n",
+			msg:    testMessage("30", "!ping", false),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "suggestion with second prefix",
+			config: "prefix: [\"!\", \"?\"]\nsuggest_commands: true\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "?pnig", false),
+			want:   []string{"Did you mean `?ping`?"},
+		},
+		{
+			name:   "DM",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			msg:    inDM(testMessage("30", "!ping", false)),
+		},
+		{
+			name:   "DM allowed",
+			config: "prefix: \"!\"\nallow_dm: true\nwhitelist_enabled: true\nwhitelist: [\"30\"]\ncommands:\n  ping: pong\n",
+			msg:    inDM(testMessage("30", "!ping", false)),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "whitelisted role",
+			config: "prefix: \"!\"\nallow_dm: true\nwhitelist_enabled: true\nwhitelist_roles: [\"50\"]\ncommands:\n  ping: pong\n",
+			msg:    withRoles(testMessage("30", "!ping", false), "50"),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "whitelisted role in DM",
+			config: "prefix: \"!\"\nallow_dm: true\nwhitelist_enabled: true\nwhitelist_roles: [\"50\"]\ncommands:\n  ping: pong\n",
+			msg:    withRoles(inDM(testMessage("30", "!ping", false)), "50"),
+		},
+		{
+			name:   "case insensitive",
+			config: "prefix: \"!\"\ncase_insensitive: true\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!PING", false),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "case sensitive",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("30", "!PING", false),
+		},
+		{
+			name:   "self",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage(botID, "!ping", true),
+		},
+		{
+			name:   "other bot",
+			config: "prefix: \"!\"\ncommands:\n  ping: pong\n",
+			msg:    testMessage("32", "!ping", true),
+		},
+		{
+			name:   "other bot not ignored",
+			config: "prefix: \"!\"\nignore_bots: false\ncommands:\n  ping: pong\n",
+			msg:    testMessage("32", "!ping", true),
+			want:   []string{"pong"},
+		},
+		{
+			name:   "self with bots not ignored",
+			config: "prefix: \"!\"\nignore_bots: false\ncommands:\n  ping: pong\n",
+			msg:    testMessage(botID, "!ping", true),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			resetTrackers()
+			fake := &fakeMessenger{}
+			h := &MessageHandler{Session: fake, BotID: botID, Synchronous: true}
+			h.Handle(tt.msg)
+
+			var got []string
+			for _, msg := range fake.messages() {
+				got = append(got, msg.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAllowSelf(t *testing.T) {
+	const botID = "1"
+	tests := []struct {
+		name      string
+		allowSelf bool
+		msg       *discordgo.MessageCreate
+		want      []string
+	}{
+		{name: "self off", msg: testMessage(botID, "!ping", true)},
+		{name: "self on", allowSelf: true, msg: testMessage(botID, "!ping", true), want: []string{"pong"}},
+		{name: "other bot on", allowSelf: true, msg: testMessage("32", "!ping", true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, "prefix: \"!\"\ncommands:\n  ping: pong\n")
+			CommandCooldowns = newCooldownTracker()
+			fake := &fakeMessenger{}
+			h := &MessageHandler{Session: fake, BotID: botID, AllowSelf: tt.allowSelf, Synchronous: true}
+			h.Handle(tt.msg)
+
+			var got []string
+			for _, msg := range fake.messages() {
+				got = append(got, msg.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleCooldownDoesNotUseQuota(t *testing.T) {
+	useConfig(t, "prefix: \"!\"\ncooldown: 60\nuser_quota: 2\ncommands:\n  ping: pong\n")
+	Cooldowns = newCooldownTracker()
+	CommandCooldowns = newCooldownTracker()
+	Quotas = newQuotaTracker()
+	fake := &fakeMessenger{}
+	h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true}
+
+	// The first use is answered, and the rest are blocked by the cooldown.
+	for i := 0; i < 3; i++ {
+		h.Handle(testMessage("30", "!ping", false))
+	}
+	// Once the cooldown is over, the second use of the quota is answered.
+	Cooldowns = newCooldownTracker()
+	h.Handle(testMessage("30", "!ping", false))
+
+	if got := len(fake.messages()); got != 2 {
+		t.Errorf("sent %d responses, want 2", got)
+	}
+}
+
+func TestHandleDuringReload(t *testing.T) {
+	configs := []string{
+		"prefix: \"!\"\ncommands:\n  ping: pong\n",
+		"prefix: \"?\"\ncase_insensitive: true\ncommands:\n  ping: pong\n  pong: ping\n",
+	}
+	useConfig(t, configs[0])
+	fake := &fakeMessenger{}
+	h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					h.Handle(testMessage("30", "!ping", false))
+					h.Handle(testMessage("30", "?PONG", false))
+				}
+			}
+		}()
+	}
+
+	// Reload the config back and forth while messages are handled.
+	for i := 0; i < 50; i++ {
+		if err := os.WriteFile(ConfigPath, []byte(configs[i%2]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		loadConfig()
+	}
+	close(done)
+	wg.Wait()
+}
+
+// containsID is the linear scan isBlacklisted and isApproved used before
+// IDs were kept in sets, for comparison.
+func containsID(ids []string, id string) bool {
+	for _, have := range ids {
+		if have == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIDSetMatchesScan(t *testing.T) {
+	ids := []string{"30", "31", "31", "", "40"}
+	config := &Config{WhitelistEnabled: true, Whitelist: ids, Blacklist: ids, whitelist: idSet(ids), blacklist: idSet(ids)}
+	for _, id := range []string{"30", "31", "40", "", "32", "3"} {
+		want := containsID(ids, id)
+		if got := isBlacklisted(config, id); got != want {
+			t.Errorf("isBlacklisted(%q) = %v, want %v", id, got, want)
+		}
+		user := &discordgo.User{ID: id}
+		if got := isApproved(&fakeMessenger{}, config, "", user, nil); got != want {
+			t.Errorf("isApproved(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+// benchmarkIDs returns n distinct user IDs.
+func benchmarkIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(100000000000000000 + i)
+	}
+	return ids
+}
+
+func BenchmarkBlacklistSet(b *testing.B) {
+	ids := benchmarkIDs(5000)
+	config := &Config{blacklist: idSet(ids)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		isBlacklisted(config, "1")
+	}
+}
+
+func BenchmarkBlacklistScan(b *testing.B) {
+	ids := benchmarkIDs(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		containsID(ids, "1")
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     Prefixes
+		content    string
+		wantName   string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{name: "no prefix", content: "ping", wantName: "ping", wantOK: true},
+		{name: "no prefix with prefix-like text", content: "!ping", wantName: "!ping", wantOK: true},
+		{name: "multi-character prefix", prefix: Prefixes{"!!"}, content: "!!ping", wantName: "ping", wantPrefix: "!!", wantOK: true},
+		{name: "partial multi-character prefix", prefix: Prefixes{"!!"}, content: "!ping"},
+		{name: "missing prefix", prefix: Prefixes{"!"}, content: "ping"},
+		{name: "only the prefix", prefix: Prefixes{"!"}, content: "!"},
+		{name: "second prefix", prefix: Prefixes{"!", "?"}, content: "?ping", wantName: "ping", wantPrefix: "?", wantOK: true},
+		{name: "first matching prefix", prefix: Prefixes{"!", "!!"}, content: "!!ping", wantName: "!ping", wantPrefix: "!", wantOK: true},
+		{name: "no prefix matched", prefix: Prefixes{"!", "?"}, content: ".ping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Prefix: tt.prefix}
+			name, prefix, ok := parseCommand(config, tt.content, false)
+			if name != tt.wantName || prefix != tt.wantPrefix || ok != tt.wantOK {
+				t.Errorf("parseCommand(%q) = %q, %q, %v; want %q, %q, %v", tt.content, name, prefix, ok, tt.wantName, tt.wantPrefix, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsApprovedRoles(t *testing.T) {
+	config := &Config{WhitelistEnabled: true, WhitelistRoles: []string{"50"}, whitelist: idSet([]string{"30"})}
+	withRole := &discordgo.Member{User: &discordgo.User{ID: "31"}, Roles: []string{"49", "50"}}
+	withoutRole := &discordgo.Member{User: &discordgo.User{ID: "31"}, Roles: []string{"49"}}
+	tests := []struct {
+		name    string
+		userID  string
+		guildID string
+		member  *discordgo.Member
+		fetched *discordgo.Member
+		want    bool
+	}{
+		{name: "whitelisted user", userID: "30", guildID: "10", member: withoutRole, want: true},
+		{name: "member with role", userID: "31", guildID: "10", member: withRole, want: true},
+		{name: "member without role", userID: "31", guildID: "10", member: withoutRole},
+		{name: "fetched member with role", userID: "31", guildID: "10", fetched: withRole, want: true},
+		{name: "fetched member without role", userID: "31", guildID: "10", fetched: withoutRole},
+		{name: "DM", userID: "31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{member: tt.fetched}
+			if got := isApproved(fake, config, tt.guildID, &discordgo.User{ID: tt.userID}, tt.member); got != tt.want {
+				t.Errorf("isApproved = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigPathEnvIsLoaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.yaml")
+	if err := os.WriteFile(path, []byte("commands:\n  ping: pong\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	getenv := func(key string) string {
+		if key == "CONFIG_PATH" {
+			return path
+		}
+		return ""
+	}
+	if err := parseFlags(nil, getenv); err != nil {
+		t.Fatalf("parseFlags: %v", err)
+	}
+	ConfigLoaded = false
+	loadConfig()
+	if _, ok := CurrentConfig.Load().lookup["ping"]; !ok {
+		t.Errorf("config from CONFIG_PATH wasn't loaded")
+	}
+}
+
+func TestHandleChannels(t *testing.T) {
+	const config = "prefix: \"!\"\ncommands:\n  rules:\n    output: Be nice\n    channels: [\"20\"]\n  ping: pong\n"
+	tests := []struct {
+		name string
+		msg  *discordgo.MessageCreate
+		want []string
+	}{
+		{name: "allowed channel", msg: testMessage("30", "!rules", false), want: []string{"Be nice"}},
+		{name: "other channel", msg: inChannel(testMessage("30", "!rules", false), "21")},
+		{name: "unrestricted command", msg: inChannel(testMessage("30", "!ping", false), "21"), want: []string{"pong"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handleMessages(t, config, tt.msg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAllowedChannels(t *testing.T) {
+	const config = "prefix: \"!\"\nallowed_channels: [\"20\", \"40\"]\nunknown_command_message: \"No {{.Command}} here\"\ncommands:\n  ping: pong\n  modmail:\n    output: Help!\n    target_channel: \"40\"\n  report:\n    output: Reported\n    target_channel: \"41\"\n"
+	tests := []struct {
+		name string
+		msg  *discordgo.MessageCreate
+		want []string
+	}{
+		{name: "allowed channel", msg: testMessage("30", "!ping", false), want: []string{"pong"}},
+		{name: "disallowed channel", msg: inChannel(testMessage("30", "!ping", false), "21")},
+		{name: "allowed target channel", msg: inChannel(testMessage("30", "!modmail", false), "21"), want: []string{"Help!"}},
+		{name: "disallowed target channel", msg: testMessage("30", "!report", false)},
+		{name: "unknown command in allowed channel", msg: testMessage("30", "!dance", false), want: []string{"No dance here"}},
+		{name: "unknown command in disallowed channel", msg: inChannel(testMessage("30", "!dance", false), "21")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handleMessages(t, config, tt.msg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildLookupAliases(t *testing.T) {
+	logs := captureLogs(t, "text", slog.LevelWarn)
+	config := &Config{Commands: map[string]CommandConfig{
+		"help": {Output: Responses{{Text: "Ask away"}}, Aliases: []string{"h", "?"}},
+		"hi":   {Output: Responses{{Text: "Hi"}}, Aliases: []string{"h", "help", "hey"}},
+	}}
+	buildLookup(config)
+
+	// Commands are added before aliases, and aliases in name order.
+	want := map[string]string{"help": "help", "h": "help", "?": "help", "hi": "hi", "hey": "hi"}
+	for key, name := range want {
+		if cmd := config.lookup[key]; cmd == nil || cmd.Name != name {
+			t.Errorf("lookup[%q] = %v, want %s", key, cmd, name)
+		}
+	}
+	for _, key := range []string{"key=h", "key=help"} {
+		if !strings.Contains(logs.String(), key) {
+			t.Errorf("conflict %s wasn't logged: %s", key, logs.String())
+		}
+	}
+}
+
+func TestHandleDeleteTrigger(t *testing.T) {
+	const config = "prefix: \"!\"\ndelete_trigger: true\ncommands:\n  ping: pong\n"
+	tests := []struct {
+		name     string
+		sendErrs []error
+		want     []string
+	}{
+		{name: "sent", want: []string{"200"}},
+		{name: "send failed", sendErrs: []error{errors.New("missing access")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{sendErrs: tt.sendErrs}
+			handleWith(t, fake, config, testMessage("30", "!ping", false))
+			if !reflect.DeepEqual(fake.deleted, tt.want) {
+				t.Errorf("deleted %q, want %q", fake.deleted, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWord(t *testing.T) {
+	tests := []struct {
+		in, first, rest string
+	}{
+		{in: "", first: "", rest: ""},
+		{in: "roll", first: "roll", rest: ""},
+		{in: "roll d20", first: "roll", rest: "d20"},
+		{in: "  roll \t d20  twice ", first: "roll", rest: "d20  twice"},
+	}
+	for _, tt := range tests {
+		first, rest := splitWord(tt.in)
+		if first != tt.first || rest != tt.rest {
+			t.Errorf("splitWord(%q) = %q, %q, want %q, %q", tt.in, first, rest, tt.first, tt.rest)
+		}
+	}
+}
+
+func TestHandleTargetChannel(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []sentMessage
+	}{
+		{
+			name:   "origin",
+			config: "prefix: \"!\"\ncommands:\n  modmail: Sent\n",
+			want:   []sentMessage{{ChannelID: "20", Content: "Sent"}},
+		},
+		{
+			name:   "target",
+			config: "prefix: \"!\"\ncommands:\n  modmail:\n    output: \"{{.User}} needs help\"\n    target_channel: \"40\"\n",
+			want:   []sentMessage{{ChannelID: "40", Content: "user-30 needs help"}},
+		},
+		{
+			name:   "target with acknowledgement",
+			config: "prefix: \"!\"\ncommands:\n  modmail:\n    output: \"{{.User}} needs help\"\n    target_channel: \"40\"\n    acknowledge: \"Thanks, {{.User}}\"\n",
+			want:   []sentMessage{{ChannelID: "40", Content: "user-30 needs help"}, {ChannelID: "20", Content: "Thanks, user-30"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			handleWith(t, fake, tt.config, testMessage("30", "!modmail", false))
+			if got := fake.messages(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
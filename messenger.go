@@ -0,0 +1,44 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Messenger is the part of a Discord session used to respond to messages.
+// It is satisfied by *discordgo.Session.
+type Messenger interface {
+	Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error
+	ChannelTyping(channelID string, options ...discordgo.RequestOption) error
+	MessageThreadStartComplex(channelID, messageID string, data *discordgo.ThreadStart, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+	MessageReactionsRemoveAll(channelID, messageID string, options ...discordgo.RequestOption) error
+	MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error
+	GuildMemberTimeout(guildID, userID string, until *time.Time, options ...discordgo.RequestOption) error
+	GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error)
+	UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error)
+	WebhookWithToken(webhookID, token string, options ...discordgo.RequestOption) (*discordgo.Webhook, error)
+	WebhookExecute(webhookID, token string, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// MessageHandler responds to messages.
+type MessageHandler struct {
+	// Session is used to respond to messages.
+	Session Messenger
+	// BotID is the bot's user ID.
+	BotID string
+	// AllowSelf defines if the bot's own messages are handled like any
+	// other. It is meant only for test setups.
+	AllowSelf bool
+	// GuildLocale returns the preferred locale of the guild with the given
+	// ID, if known. It may be nil, in which case responses are never
+	// translated.
+	GuildLocale func(guildID string) string
+	// Synchronous defines if Handle sends every response before returning,
+	// rather than sending sequences in the background. Commands aren't
+	// confirmed, since no reactions can be waited for.
+	Synchronous bool
+}
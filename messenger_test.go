@@ -0,0 +1,216 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// sentMessage is a message sent through a fakeMessenger.
+type sentMessage struct {
+	ChannelID string
+	Content   string
+	Reference *discordgo.MessageReference
+}
+
+// fakeMessenger is a Messenger that records what is sent instead of sending
+// it.
+type fakeMessenger struct {
+	mu   sync.Mutex
+	sent []sentMessage
+	// sendErrs are returned by the next sends, in order, instead of
+	// sending.
+	sendErrs []error
+	// reactions are the emoji added to messages, in order.
+	reactions []string
+	// deleted are the IDs of the messages deleted, in order.
+	deleted []string
+	// cleared are the IDs of the messages whose reactions were removed, in
+	// order.
+	cleared []string
+	// timedOut are the IDs of the users timed out, in order.
+	timedOut []string
+	// dmErr is returned instead of opening DM channels, if set.
+	dmErr error
+	// member is returned as the member info of every user.
+	member *discordgo.Member
+}
+
+// messages returns the messages sent so far.
+func (f *fakeMessenger) messages() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.sent...)
+}
+
+func (f *fakeMessenger) record(channelID, content string, reference *discordgo.MessageReference) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sendErrs) > 0 {
+		err := f.sendErrs[0]
+		f.sendErrs = f.sendErrs[1:]
+		return nil, err
+	}
+	f.sent = append(f.sent, sentMessage{ChannelID: channelID, Content: content, Reference: reference})
+	return &discordgo.Message{ID: "100", ChannelID: channelID, Content: content}, nil
+}
+
+func (f *fakeMessenger) Channel(channelID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: channelID, Type: discordgo.ChannelTypeGuildText}, nil
+}
+
+func (f *fakeMessenger) UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	if f.dmErr != nil {
+		return nil, f.dmErr
+	}
+	return &discordgo.Channel{ID: "dm-" + recipientID, Type: discordgo.ChannelTypeDM}, nil
+}
+
+func (f *fakeMessenger) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return f.record(channelID, data.Content, data.Reference)
+}
+
+func (f *fakeMessenger) ChannelMessageDelete(channelID, messageID string, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, messageID)
+	return nil
+}
+
+func (f *fakeMessenger) ChannelTyping(channelID string, options ...discordgo.RequestOption) error {
+	return nil
+}
+
+func (f *fakeMessenger) MessageThreadStartComplex(channelID, messageID string, data *discordgo.ThreadStart, options ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{ID: "thread-" + messageID, Type: discordgo.ChannelTypeGuildPublicThread}, nil
+}
+
+func (f *fakeMessenger) MessageReactionsRemoveAll(channelID, messageID string, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cleared = append(f.cleared, messageID)
+	return nil
+}
+
+func (f *fakeMessenger) MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reactions = append(f.reactions, emojiID)
+	return nil
+}
+
+func (f *fakeMessenger) GuildMemberTimeout(guildID, userID string, until *time.Time, options ...discordgo.RequestOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.timedOut = append(f.timedOut, userID)
+	return nil
+}
+
+func (f *fakeMessenger) GuildMember(guildID, userID string, options ...discordgo.RequestOption) (*discordgo.Member, error) {
+	if f.member == nil {
+		return &discordgo.Member{GuildID: guildID, User: &discordgo.User{ID: userID}}, nil
+	}
+	return f.member, nil
+}
+
+func (f *fakeMessenger) UserChannelPermissions(userID, channelID string, fetchOptions ...discordgo.RequestOption) (int64, error) {
+	return discordgo.PermissionAll, nil
+}
+
+func (f *fakeMessenger) WebhookWithToken(webhookID, token string, options ...discordgo.RequestOption) (*discordgo.Webhook, error) {
+	return &discordgo.Webhook{ID: webhookID, ChannelID: "webhook-channel-" + webhookID}, nil
+}
+
+func (f *fakeMessenger) WebhookExecute(webhookID, token string, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return f.record("webhook-"+webhookID, data.Content, nil)
+}
+
+// useConfig loads the config in data as the current config, as if it were
+// read from a file.
+func useConfig(t testing.TB, data string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ConfigPath = path
+	ConfigLoaded = false
+	loadConfig()
+	return CurrentConfig.Load()
+}
+
+// testMessage returns a message with the given content, sent by the user
+// in a guild channel.
+func testMessage(userID, content string, bot bool) *discordgo.MessageCreate {
+	return &discordgo.MessageCreate{Message: &discordgo.Message{
+		ID:        "200",
+		ChannelID: "20",
+		GuildID:   "10",
+		Content:   content,
+		Author:    &discordgo.User{ID: userID, Username: "user-" + userID, Bot: bot},
+	}}
+}
+
+// handleMessages loads the config in data, handles msgs in order with fresh
+// cooldowns and quotas, and returns the contents of the messages sent.
+func handleMessages(t *testing.T, data string, msgs ...*discordgo.MessageCreate) []string {
+	t.Helper()
+	fake := &fakeMessenger{}
+	handleWith(t, fake, data, msgs...)
+	var sent []string
+	for _, msg := range fake.messages() {
+		sent = append(sent, msg.Content)
+	}
+	return sent
+}
+
+// handleWith is like handleMessages but handles msgs with the given
+// messenger.
+func handleWith(t *testing.T, fake *fakeMessenger, data string, msgs ...*discordgo.MessageCreate) {
+	t.Helper()
+	useConfig(t, data)
+	resetTrackers()
+	h := &MessageHandler{Session: fake, BotID: "1", Synchronous: true}
+	for _, m := range msgs {
+		h.Handle(m)
+	}
+}
+
+// resetTrackers clears every user's cooldowns, quotas, and responses.
+func resetTrackers() {
+	Cooldowns = newCooldownTracker()
+	CommandCooldowns = newCooldownTracker()
+	Quotas = newQuotaTracker()
+	Responded = newRespondedTracker()
+}
+
+// inChannel returns m moved to the channel.
+func inChannel(m *discordgo.MessageCreate, channelID string) *discordgo.MessageCreate {
+	m.ChannelID = channelID
+	return m
+}
+
+// inDM returns m moved to a DM with its author.
+func inDM(m *discordgo.MessageCreate) *discordgo.MessageCreate {
+	m.GuildID = ""
+	m.Member = nil
+	return m
+}
+
+// withRoles returns m with the author's member info holding the roles.
+func withRoles(m *discordgo.MessageCreate, roles ...string) *discordgo.MessageCreate {
+	m.Member = &discordgo.Member{User: m.Author, Roles: roles}
+	return m
+}
+
+// mentioning returns m with the users mentioned.
+func mentioning(m *discordgo.MessageCreate, userIDs ...string) *discordgo.MessageCreate {
+	for _, id := range userIDs {
+		m.Mentions = append(m.Mentions, &discordgo.User{ID: id})
+	}
+	return m
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sendLatencyBuckets are the upper bounds in seconds of the send latency
+// histogram's buckets, from 1ms to 5s.
+var sendLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// SendLatency records how long sending messages to Discord takes.
+var SendLatency = newHistogram(sendLatencyBuckets)
+
+// histogram counts observed durations in buckets, in the form exposed by
+// Prometheus.
+type histogram struct {
+	mu sync.Mutex
+	// buckets are the upper bounds in seconds of each bucket, in order.
+	buckets []float64
+	// counts holds the number of observations in each bucket, not including
+	// those in lower buckets.
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// newHistogram returns an empty histogram with the given bucket upper
+// bounds, which must be in increasing order.
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// observe records a duration.
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// time calls f and records how long it took.
+func (h *histogram) time(f func() error) error {
+	start := time.Now()
+	err := f()
+	h.observe(time.Since(start))
+	return err
+}
+
+// write writes the histogram to w in the Prometheus text format, with the
+// given name and help text.
+func (h *histogram) write(w io.Writer, name, help string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// metricsHandler serves the bot's metrics in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	SendLatency.write(w, "mad_black_cat_send_duration_seconds", "Time taken to send messages to Discord.")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramWrite(t *testing.T) {
+	h := newHistogram([]float64{0.01, 0.1, 1})
+	for _, d := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 50 * time.Millisecond, 2 * time.Second} {
+		h.observe(d)
+	}
+
+	var b strings.Builder
+	h.write(&b, "send_seconds", "Time to send.")
+	want := `# HELP send_seconds Time to send.
+# TYPE send_seconds histogram
+send_seconds_bucket{le="0.01"} 2
+send_seconds_bucket{le="0.1"} 3
+send_seconds_bucket{le="1"} 3
+send_seconds_bucket{le="+Inf"} 4
+send_seconds_sum 2.065
+send_seconds_count 4
+`
+	if got := b.String(); got != want {
+		t.Errorf("write =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSendObservesLatency(t *testing.T) {
+	previous := SendLatency
+	SendLatency = newHistogram(sendLatencyBuckets)
+	t.Cleanup(func() { SendLatency = previous })
+
+	handleMessages(t, "prefix: \"!\"\ncommands:\n  ping: pong\n", testMessage("30", "!ping", false), testMessage("31", "!ping", false))
+	SendLatency.mu.Lock()
+	count := SendLatency.count
+	SendLatency.mu.Unlock()
+	if count != 2 {
+		t.Errorf("observed %d sends, want 2", count)
+	}
+
+	rec := httptest.NewRecorder()
+	metricsHandler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "mad_black_cat_send_duration_seconds_count 2\n") {
+		t.Errorf("metrics %q don't count 2 sends", rec.Body)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "log/slog"
+
+// currentConfigVersion is the version of the config format this build
+// writes and expects. Unversioned configs are version 1.
+const currentConfigVersion = 2
+
+// migrations upgrade a config from the version given by their key to the
+// next version.
+var migrations = map[int]func(config *Config){
+	1: migrateV1,
+}
+
+// migrateV1 upgrades a version 1 config, where commands matched by pattern
+// were marked with regex: true, to version 2, which uses match: regex.
+// In version 1, regex took precedence over any match mode, so it still
+// does. Commands given as responses alone are already read into the full
+// form when decoded.
+func migrateV1(config *Config) {
+	for name, cmd := range config.Commands {
+		if cmd.Regex {
+			if cmd.Match != "" && cmd.Match != matchRegex {
+				slog.Warn("command sets both regex and match; keeping regex", "command", name, "match", cmd.Match)
+			}
+			cmd.Regex = false
+			cmd.Match = matchRegex
+			config.Commands[name] = cmd
+		}
+	}
+}
+
+// migrateConfig upgrades config to the current version, logging each
+// upgrade. Configs from newer versions are loaded as they are, on a best
+// effort basis.
+func migrateConfig(config *Config) {
+	version := config.SchemaVersion
+	switch {
+	case version == 0:
+		version = 1
+	case version < 0:
+		// validate reports it.
+		return
+	}
+	if version > currentConfigVersion {
+		slog.Warn("config version is newer than supported; loading it anyway", "version", version, "supported", currentConfigVersion)
+		return
+	}
+
+	for ; version < currentConfigVersion; version++ {
+		migrations[version](config)
+		slog.Info("migrated config", "from", version, "to", version+1)
+	}
+	config.SchemaVersion = version
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     int
+		command     CommandConfig
+		wantMatch   string
+		wantRegex   bool
+		wantVersion int
+	}{
+		{name: "unversioned exact", command: CommandConfig{}, wantMatch: "", wantVersion: 2},
+		{name: "unversioned regex", command: CommandConfig{Regex: true}, wantMatch: matchRegex, wantVersion: 2},
+		{name: "v1 regex", version: 1, command: CommandConfig{Regex: true}, wantMatch: matchRegex, wantVersion: 2},
+		{name: "v1 regex over contains", version: 1, command: CommandConfig{Regex: true, Match: matchContains}, wantMatch: matchRegex, wantVersion: 2},
+		{name: "v1 contains", version: 1, command: CommandConfig{Match: matchContains}, wantMatch: matchContains, wantVersion: 2},
+		{name: "v2 untouched", version: 2, command: CommandConfig{Regex: true}, wantRegex: true, wantVersion: 2},
+		{name: "newer untouched", version: 3, command: CommandConfig{Regex: true}, wantRegex: true, wantVersion: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{SchemaVersion: tt.version, Commands: map[string]CommandConfig{"ping": tt.command}}
+			migrateConfig(config)
+
+			got := config.Commands["ping"]
+			if got.Match != tt.wantMatch || got.Regex != tt.wantRegex {
+				t.Errorf("match = %q, regex = %v; want %q, %v", got.Match, got.Regex, tt.wantMatch, tt.wantRegex)
+			}
+			if config.SchemaVersion != tt.wantVersion {
+				t.Errorf("version = %d, want %d", config.SchemaVersion, tt.wantVersion)
+			}
+		})
+	}
+}
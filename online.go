@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// Announced reports whether the bot has announced that it is online.
+	Announced atomic.Bool
+	// Readies counts the Ready events received, the first of which is for the
+	// initial connection and the rest for reconnections.
+	Readies atomic.Int64
+)
+
+// OnlineData is the data available to the online message template.
+type OnlineData struct {
+	Version    string
+	ShardID    int
+	ShardCount int
+	// Reconnect defines if the bot is announcing that it has reconnected,
+	// rather than started.
+	Reconnect bool
+}
+
+func ready(s *discordgo.Session, r *discordgo.Ready) {
+	if Readies.Add(1) > 1 {
+		restoreState(s, CurrentConfig.Load())
+		announceOnline(s, CurrentConfig.Load(), true)
+	}
+}
+
+func resumed(s *discordgo.Session, r *discordgo.Resumed) {
+	restoreState(s, CurrentConfig.Load())
+	announceOnline(s, CurrentConfig.Load(), true)
+}
+
+// shouldAnnounce reports whether to announce that the bot is online. It is
+// announced once when started, and on reconnects only if announceReconnects
+// is set.
+func shouldAnnounce(announced *atomic.Bool, reconnect, announceReconnects bool) bool {
+	if !reconnect {
+		return announced.CompareAndSwap(false, true)
+	}
+	return announceReconnects && announced.Load()
+}
+
+// onlineMessage renders the online message with the given data.
+func onlineMessage(tmpl *template.Template, data OnlineData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// announceOnline sends the online message to the online channel, if
+// enabled and not already announced.
+func announceOnline(s *discordgo.Session, config *Config, reconnect bool) {
+	if config.OnlineChannel == "" || config.onlineMessage == nil {
+		return
+	}
+	if !shouldAnnounce(&Announced, reconnect, config.AnnounceReconnects) {
+		return
+	}
+
+	text, err := onlineMessage(config.onlineMessage, OnlineData{
+		Version:    Version,
+		ShardID:    s.ShardID,
+		ShardCount: s.ShardCount,
+		Reconnect:  reconnect,
+	})
+	if err != nil {
+		slog.Error("error rendering online message", "err", err)
+		return
+	}
+
+	ctx, cancel := requestContext(config)
+	defer cancel()
+	err = waitToSend(ctx, Limiter, config.RateLimitMode)
+	if err == nil {
+		_, err = sendMessage(s, config, config.OnlineChannel, &discordgo.MessageSend{Content: text}, discordgo.WithContext(ctx))
+	}
+	if err != nil {
+		slog.Error("error sending online message", "channel_id", config.OnlineChannel, "err", err)
+		return
+	}
+	slog.Info("announced online", "channel_id", config.OnlineChannel, "reconnect", reconnect)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestShouldAnnounce(t *testing.T) {
+	tests := []struct {
+		name               string
+		announceReconnects bool
+		events             []bool
+		want               []bool
+	}{
+		{name: "once", events: []bool{false, true, false, true}, want: []bool{true, false, false, false}},
+		{name: "reconnects", announceReconnects: true, events: []bool{false, true, true, false}, want: []bool{true, true, true, false}},
+		{name: "reconnect before start", announceReconnects: true, events: []bool{true, false}, want: []bool{false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var announced atomic.Bool
+			for i, reconnect := range tt.events {
+				if got := shouldAnnounce(&announced, reconnect, tt.announceReconnects); got != tt.want[i] {
+					t.Errorf("event %d (reconnect %t): shouldAnnounce = %t, want %t", i, reconnect, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOnlineMessage(t *testing.T) {
+	config := useConfig(t, "prefix: \"!\"\nonline_channel: \"20\"\nonline_message: \"{{if .Reconnect}}Back{{else}}I'm online{{end}}! v{{.Version}} shard {{.ShardID}}/{{.ShardCount}}\"\n")
+	if config.onlineMessage == nil {
+		t.Fatal("online message wasn't parsed")
+	}
+
+	tests := []struct {
+		data OnlineData
+		want string
+	}{
+		{data: OnlineData{Version: "1.2.0", ShardID: 0, ShardCount: 1}, want: "I'm online! v1.2.0 shard 0/1"},
+		{data: OnlineData{Version: "1.2.0", ShardID: 2, ShardCount: 4, Reconnect: true}, want: "Back! v1.2.0 shard 2/4"},
+	}
+	for _, tt := range tests {
+		got, err := onlineMessage(config.onlineMessage, tt.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("onlineMessage(%+v) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
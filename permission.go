@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// permissionUnknown is the permission required by commands configured with
+// an unknown permission name. No one has it.
+const permissionUnknown int64 = -1
+
+// permissionNames maps the names used in the config to permissions.
+var permissionNames = map[string]int64{
+	"create_instant_invite": discordgo.PermissionCreateInstantInvite,
+	"kick_members":          discordgo.PermissionKickMembers,
+	"ban_members":           discordgo.PermissionBanMembers,
+	"administrator":         discordgo.PermissionAdministrator,
+	"manage_channels":       discordgo.PermissionManageChannels,
+	"manage_guild":          discordgo.PermissionManageGuild,
+	"add_reactions":         discordgo.PermissionAddReactions,
+	"view_audit_log":        discordgo.PermissionViewAuditLogs,
+	"view_channel":          discordgo.PermissionViewChannel,
+	"send_messages":         discordgo.PermissionSendMessages,
+	"manage_messages":       discordgo.PermissionManageMessages,
+	"embed_links":           discordgo.PermissionEmbedLinks,
+	"attach_files":          discordgo.PermissionAttachFiles,
+	"read_message_history":  discordgo.PermissionReadMessageHistory,
+	"mention_everyone":      discordgo.PermissionMentionEveryone,
+	"change_nickname":       discordgo.PermissionChangeNickname,
+	"manage_nicknames":      discordgo.PermissionManageNicknames,
+	"manage_roles":          discordgo.PermissionManageRoles,
+	"manage_webhooks":       discordgo.PermissionManageWebhooks,
+	"manage_threads":        discordgo.PermissionManageThreads,
+	"moderate_members":      discordgo.PermissionModerateMembers,
+}
+
+// resolvePermission returns the named permission. Unknown names are logged
+// and resolve to permissionUnknown, so that the command is denied to
+// everyone rather than allowed to everyone.
+func resolvePermission(command, name string) int64 {
+	permission, ok := permissionNames[name]
+	if !ok {
+		slog.Warn("unknown permission; denying command to everyone", "command", command, "permission", name)
+		return permissionUnknown
+	}
+	return permission
+}
+
+// permitted reports whether a user with the given permissions has the
+// required permission. Administrators have every permission.
+func permitted(permissions, required int64) bool {
+	switch {
+	case required == 0:
+		return true
+	case required == permissionUnknown:
+		return false
+	case permissions&discordgo.PermissionAdministrator != 0:
+		return true
+	}
+	return permissions&required == required
+}
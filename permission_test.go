@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestResolvePermission(t *testing.T) {
+	tests := []struct {
+		name string
+		want int64
+	}{
+		{name: "manage_messages", want: discordgo.PermissionManageMessages},
+		{name: "administrator", want: discordgo.PermissionAdministrator},
+		{name: "Manage Messages", want: permissionUnknown},
+		{name: "fly", want: permissionUnknown},
+	}
+	for _, tt := range tests {
+		if got := resolvePermission("purge", tt.name); got != tt.want {
+			t.Errorf("resolvePermission(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPermitted(t *testing.T) {
+	const (
+		send   = discordgo.PermissionSendMessages
+		manage = discordgo.PermissionManageMessages
+		admin  = discordgo.PermissionAdministrator
+	)
+	tests := []struct {
+		name        string
+		permissions int64
+		required    int64
+		want        bool
+	}{
+		{name: "nothing required", permissions: 0, required: 0, want: true},
+		{name: "has permission", permissions: send | manage, required: manage, want: true},
+		{name: "lacks permission", permissions: send, required: manage},
+		{name: "administrator", permissions: admin, required: manage, want: true},
+		{name: "unknown permission", permissions: send | manage, required: permissionUnknown},
+		{name: "unknown permission for administrator", permissions: admin, required: permissionUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permitted(tt.permissions, tt.required); got != tt.want {
+				t.Errorf("permitted(%b, %b) = %t, want %t", tt.permissions, tt.required, got, tt.want)
+			}
+		})
+	}
+}
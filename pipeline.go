@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Middleware transforms a response's text before it is sent.
+type Middleware func(ctx context.Context, response string) string
+
+// middlewares maps the names used in the config to built-in middlewares.
+var middlewares = map[string]Middleware{
+	"trim": func(_ context.Context, response string) string {
+		return strings.TrimSpace(response)
+	},
+	"upper": func(_ context.Context, response string) string {
+		return strings.ToUpper(response)
+	},
+	"lower": func(_ context.Context, response string) string {
+		return strings.ToLower(response)
+	},
+}
+
+// resolvePipeline returns the named middlewares in order. Unknown names are
+// skipped; they are reported by validate.
+func resolvePipeline(names []string) []Middleware {
+	var pipeline []Middleware
+	for _, name := range names {
+		if m, ok := middlewares[name]; ok {
+			pipeline = append(pipeline, m)
+		}
+	}
+	return pipeline
+}
+
+// applyPipeline runs the text of response through each middleware in
+// pipeline in order, including the titles and descriptions of embeds.
+func applyPipeline(ctx context.Context, pipeline []Middleware, response *discordgo.MessageSend) {
+	if len(pipeline) == 0 {
+		return
+	}
+	apply := func(s string) string {
+		for _, m := range pipeline {
+			s = m(ctx, s)
+		}
+		return s
+	}
+
+	if response.Content != "" {
+		response.Content = apply(response.Content)
+	}
+	for _, embed := range response.Embeds {
+		embed.Title = apply(embed.Title)
+		embed.Description = apply(embed.Description)
+	}
+}
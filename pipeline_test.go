@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestApplyPipeline(t *testing.T) {
+	exclaim := func(_ context.Context, response string) string { return response + "!" }
+	tests := []struct {
+		name     string
+		pipeline []Middleware
+		want     string
+	}{
+		{name: "none", want: "  Hello there  "},
+		{name: "trim then upper", pipeline: resolvePipeline([]string{"trim", "upper"}), want: "HELLO THERE"},
+		{name: "in order", pipeline: []Middleware{exclaim, middlewares["trim"]}, want: "Hello there  !"},
+		{name: "unknown skipped", pipeline: resolvePipeline([]string{"shout", "lower"}), want: "  hello there  "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &discordgo.MessageSend{Content: "  Hello there  "}
+			applyPipeline(context.Background(), tt.pipeline, response)
+			if response.Content != tt.want {
+				t.Errorf("content = %q, want %q", response.Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPipelineEmbed(t *testing.T) {
+	response := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{{Title: " Weather ", Description: " sunny "}}}
+	applyPipeline(context.Background(), resolvePipeline([]string{"trim", "upper"}), response)
+	if embed := response.Embeds[0]; embed.Title != "WEATHER" || embed.Description != "SUNNY" {
+		t.Errorf("embed = %q, %q, want %q, %q", embed.Title, embed.Description, "WEATHER", "SUNNY")
+	}
+}
+
+func TestHandlePipeline(t *testing.T) {
+	sent := handleMessages(t, "prefix: \"!\"\nresponse_pipeline: [trim, upper]\ncommands:\n  ping: \" pong \"\n", testMessage("30", "!ping", false))
+	if len(sent) != 1 || sent[0] != "PONG" {
+		t.Errorf("sent %q, want %q", sent, "PONG")
+	}
+}
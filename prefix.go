@@ -0,0 +1,58 @@
+package main
+
+import "strings"
+
+// Prefixes is a list of prefixes that commands may be used with. In YAML, it
+// may be given as either a single prefix or a list of prefixes.
+type Prefixes []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (p *Prefixes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Accept a single prefix.
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*p = Prefixes{single}
+		return nil
+	}
+
+	// Otherwise, expect a list of prefixes.
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// set reports whether any prefix is set.
+func (p Prefixes) set() bool {
+	for _, prefix := range p {
+		if prefix != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// primary returns the first prefix, which is used where no prefix was typed.
+func (p Prefixes) primary() string {
+	if len(p) == 0 {
+		return ""
+	}
+	return p[0]
+}
+
+// match returns the first prefix that content starts with, if any, and the
+// rest of content. If caseInsensitive is set, prefixes match regardless of
+// case, and the prefix is returned as configured.
+func (p Prefixes) match(content string, caseInsensitive bool) (string, string, bool) {
+	for _, prefix := range p {
+		if prefix == "" || len(content) < len(prefix) {
+			continue
+		}
+		if content[:len(prefix)] == prefix || (caseInsensitive && strings.EqualFold(content[:len(prefix)], prefix)) {
+			return prefix, content[len(prefix):], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestPrefixesYAML(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		want    Prefixes
+		wantErr bool
+	}{
+		{yaml: `"!"`, want: Prefixes{"!"}},
+		{yaml: `["!", "?"]`, want: Prefixes{"!", "?"}},
+		{yaml: `{a: b}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Prefixes
+		err := yaml.Unmarshal([]byte(tt.yaml), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unmarshal %s: error = %v, want error %t", tt.yaml, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unmarshal %s = %q, want %q", tt.yaml, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixesMatch(t *testing.T) {
+	prefixes := Prefixes{"!", "hey bot "}
+	tests := []struct {
+		content         string
+		caseInsensitive bool
+		wantPrefix      string
+		wantRest        string
+		wantOK          bool
+	}{
+		{content: "!ping", wantPrefix: "!", wantRest: "ping", wantOK: true},
+		{content: "hey bot ping", wantPrefix: "hey bot ", wantRest: "ping", wantOK: true},
+		{content: "Hey Bot ping"},
+		{content: "Hey Bot ping", caseInsensitive: true, wantPrefix: "hey bot ", wantRest: "ping", wantOK: true},
+		{content: "?ping"},
+		{content: ""},
+	}
+	for _, tt := range tests {
+		prefix, rest, ok := prefixes.match(tt.content, tt.caseInsensitive)
+		if prefix != tt.wantPrefix || rest != tt.wantRest || ok != tt.wantOK {
+			t.Errorf("match(%q, %t) = %q, %q, %t, want %q, %q, %t", tt.content, tt.caseInsensitive, prefix, rest, ok, tt.wantPrefix, tt.wantRest, tt.wantOK)
+		}
+	}
+}
+
+func TestHandleCaseInsensitivePrefix(t *testing.T) {
+	const config = "prefix: [\"!\", \"Hey Bot \"]\ncase_insensitive: true\ncommands:\n  Ping: pong\n  hello world: hi\n"
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{content: "!ping", want: []string{"pong"}},
+		{content: "!PING", want: []string{"pong"}},
+		{content: "!PiNg", want: []string{"pong"}},
+		{content: "hey bot ping", want: []string{"pong"}},
+		{content: "HEY BOT PING", want: []string{"pong"}},
+		{content: "hEy BoT Hello World", want: []string{"hi"}},
+		{content: "?ping"},
+		{content: "heybot ping"},
+	}
+	for _, tt := range tests {
+		got := handleMessages(t, config, testMessage("30", tt.content, false))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: sent %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestHandleCaseSensitivePrefix(t *testing.T) {
+	const config = "prefix: \"Hey Bot \"\ncommands:\n  ping: pong\n"
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{content: "Hey Bot ping", want: []string{"pong"}},
+		{content: "hey bot ping"},
+		{content: "Hey Bot PING"},
+	}
+	for _, tt := range tests {
+		got := handleMessages(t, config, testMessage("30", tt.content, false))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: sent %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultPresenceInterval is how often the bot's status changes, if several
+// are configured and presence_interval isn't set.
+const defaultPresenceInterval = 5 * time.Minute
+
+// presenceTypes maps the activity types used in the config to Discord's.
+var presenceTypes = map[string]discordgo.ActivityType{
+	"playing":   discordgo.ActivityTypeGame,
+	"listening": discordgo.ActivityTypeListening,
+	"watching":  discordgo.ActivityTypeWatching,
+	"competing": discordgo.ActivityTypeCompeting,
+	"custom":    discordgo.ActivityTypeCustom,
+}
+
+// Presence defines a status shown by the bot, such as "Playing !help".
+type Presence struct {
+	// Type is the activity type: "playing", the default, "listening",
+	// "watching", "competing", or "custom".
+	Type string `yaml:"type"`
+	// Text is the activity's text.
+	Text string `yaml:"text"`
+}
+
+// Presences is a list of statuses the bot rotates through. In YAML, it may
+// be given as either a single status or a list of statuses.
+type Presences []Presence
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (p *Presences) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Accept a single status.
+	var single Presence
+	if err := unmarshal(&single); err == nil {
+		*p = Presences{single}
+		return nil
+	}
+
+	// Otherwise, expect a list of statuses.
+	var list []Presence
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// presenceInterval returns how often the bot's status changes.
+func (c *Config) presenceInterval() time.Duration {
+	if c.PresenceInterval <= 0 {
+		return defaultPresenceInterval
+	}
+	return time.Duration(c.PresenceInterval) * time.Second
+}
+
+// statusData returns the status update that shows the presence.
+func statusData(p Presence) discordgo.UpdateStatusData {
+	activity := &discordgo.Activity{Name: p.Text, Type: presenceTypes[p.Type]}
+	if p.Type == "custom" {
+		// Custom statuses show their state rather than their name.
+		activity.Name = "Custom Status"
+		activity.State = p.Text
+	}
+	return discordgo.UpdateStatusData{
+		Status:     string(discordgo.StatusOnline),
+		Activities: []*discordgo.Activity{activity},
+	}
+}
+
+// presenceRotator shows the configured statuses in turn.
+type presenceRotator struct {
+	mu      sync.Mutex
+	update  func(discordgo.UpdateStatusData) error
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stopped bool
+}
+
+// newPresenceRotator returns a presenceRotator that shows statuses using
+// update.
+func newPresenceRotator(update func(discordgo.UpdateStatusData) error) *presenceRotator {
+	return &presenceRotator{update: update}
+}
+
+// start replaces any running rotation with the given statuses, changing
+// between them every interval. A single status is shown once. It does
+// nothing once the rotator has been stopped.
+func (pr *presenceRotator) start(presences Presences, interval time.Duration) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if pr.stopped {
+		return
+	}
+	pr.stopLocked()
+	if len(presences) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	pr.cancel, pr.done = cancel, done
+	go func() {
+		defer close(done)
+		pr.rotate(ctx, presences, interval)
+	}()
+}
+
+// rotate shows each of presences in turn until ctx is cancelled.
+func (pr *presenceRotator) rotate(ctx context.Context, presences Presences, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; ; i = nextPresence(i, len(presences)) {
+		err := pr.update(statusData(presences[i]))
+		if err != nil {
+			slog.Error("error updating status", "err", err)
+		}
+		if len(presences) == 1 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// nextPresence returns the index of the status shown after the one at i,
+// out of n.
+func nextPresence(i, n int) int {
+	return (i + 1) % n
+}
+
+// stop stops rotating statuses, waiting for any update to finish.
+func (pr *presenceRotator) stop() {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	pr.stopped = true
+	pr.stopLocked()
+}
+
+// stopLocked stops the running rotation, if any. pr.mu must be held.
+func (pr *presenceRotator) stopLocked() {
+	if pr.cancel != nil {
+		pr.cancel()
+		<-pr.done
+		pr.cancel, pr.done = nil, nil
+	}
+}
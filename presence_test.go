@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestStatusData(t *testing.T) {
+	tests := []struct {
+		name     string
+		presence Presence
+		want     discordgo.Activity
+	}{
+		{name: "default", presence: Presence{Text: "!help"}, want: discordgo.Activity{Name: "!help", Type: discordgo.ActivityTypeGame}},
+		{name: "watching", presence: Presence{Type: "watching", Text: "the chat"}, want: discordgo.Activity{Name: "the chat", Type: discordgo.ActivityTypeWatching}},
+		{
+			name:     "custom",
+			presence: Presence{Type: "custom", Text: "Taking commands"},
+			want:     discordgo.Activity{Name: "Custom Status", Type: discordgo.ActivityTypeCustom, State: "Taking commands"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := statusData(tt.presence)
+			if data.Status != string(discordgo.StatusOnline) {
+				t.Errorf("status = %q, want %q", data.Status, discordgo.StatusOnline)
+			}
+			if len(data.Activities) != 1 || !reflect.DeepEqual(*data.Activities[0], tt.want) {
+				t.Errorf("activities = %+v, want %+v", data.Activities, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextPresence(t *testing.T) {
+	var got []int
+	for i, n := 0, 0; n < 7; i, n = nextPresence(i, 3), n+1 {
+		got = append(got, i)
+	}
+	if want := []int{0, 1, 2, 0, 1, 2, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rotation = %v, want %v", got, want)
+	}
+	if got := nextPresence(0, 1); got != 0 {
+		t.Errorf("nextPresence(0, 1) = %d, want 0", got)
+	}
+}
+
+func TestPresencesYAML(t *testing.T) {
+	single := useConfig(t, "presence:\n  type: listening\n  text: \"!help\"\n")
+	if want := (Presences{{Type: "listening", Text: "!help"}}); !reflect.DeepEqual(single.Presence, want) {
+		t.Errorf("single presence = %+v, want %+v", single.Presence, want)
+	}
+	list := useConfig(t, "presence:\n  - text: \"!help\"\n  - type: watching\n    text: the chat\n")
+	if want := (Presences{{Text: "!help"}, {Type: "watching", Text: "the chat"}}); !reflect.DeepEqual(list.Presence, want) {
+		t.Errorf("presence list = %+v, want %+v", list.Presence, want)
+	}
+}
+
+func TestPresenceRotator(t *testing.T) {
+	var mu sync.Mutex
+	var shown []string
+	updated := make(chan struct{}, 3)
+	pr := newPresenceRotator(func(data discordgo.UpdateStatusData) error {
+		mu.Lock()
+		shown = append(shown, data.Activities[0].Name)
+		mu.Unlock()
+		select {
+		case updated <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	pr.start(Presences{{Text: "a"}, {Text: "b"}}, time.Millisecond)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-updated:
+		case <-time.After(5 * time.Second):
+			t.Fatal("status not rotated")
+		}
+	}
+	pr.stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"a", "b", "a"}; !reflect.DeepEqual(shown[:3], want) {
+		t.Errorf("shown %q, want %q first", shown, want)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+)
+
+const (
+	// queueBlock makes handlers wait for room when the send queue is full.
+	queueBlock = "block"
+	// queueDropNewest drops the job being enqueued when the send queue is
+	// full.
+	queueDropNewest = "drop_newest"
+	// queueDropOldest drops the oldest queued job to make room when the send
+	// queue is full.
+	queueDropOldest = "drop_oldest"
+
+	// defaultSendQueueSize is the size of the send queue if
+	// send_queue_size isn't set.
+	defaultSendQueueSize = 100
+)
+
+// sendQueue is a buffered queue of send jobs, run in order by a pool of
+// workers so that handlers don't wait for sends to finish.
+type sendQueue struct {
+	jobs   chan func()
+	policy string
+
+	// mu guards closed, and is held for reading while enqueueing so that
+	// jobs are never sent on a closed channel.
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// newSendQueue returns a queue holding up to size jobs, applying the given
+// policy when it is full.
+func newSendQueue(size int, policy string) *sendQueue {
+	if size <= 0 {
+		size = defaultSendQueueSize
+	}
+	if policy == "" {
+		policy = queueBlock
+	}
+	return &sendQueue{jobs: make(chan func(), size), policy: policy}
+}
+
+// start starts the given number of workers.
+func (q *sendQueue) start(workers int) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for job := range q.jobs {
+				job()
+			}
+		}()
+	}
+}
+
+// enqueue adds job to the queue, and reports whether it was added. If the
+// queue is full, the job is added or dropped according to the queue's
+// policy. Jobs are never added once the queue has been stopped.
+func (q *sendQueue) enqueue(job func()) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		slog.Warn("send queue stopped; dropping message")
+		return false
+	}
+
+	for {
+		select {
+		case q.jobs <- job:
+			return true
+		default:
+		}
+
+		switch q.policy {
+		case queueDropNewest:
+			slog.Warn("send queue full; dropping newest message")
+			return false
+		case queueDropOldest:
+			select {
+			case <-q.jobs:
+				slog.Warn("send queue full; dropping oldest message")
+			default:
+			}
+		default:
+			q.jobs <- job
+			return true
+		}
+	}
+}
+
+// stop stops accepting jobs, and waits for the workers to finish the jobs
+// already queued.
+func (q *sendQueue) stop() {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runQueue enqueues jobs recording the numbers from 1 to n on a queue of the
+// given size and policy before starting one worker, and returns the numbers
+// recorded once the queue is stopped along with those enqueued.
+func runQueue(size int, policy string, n int) (ran, added []int) {
+	q := newSendQueue(size, policy)
+	var mu sync.Mutex
+	for i := 1; i <= n; i++ {
+		i := i
+		if q.enqueue(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			ran = append(ran, i)
+		}) {
+			added = append(added, i)
+		}
+	}
+	q.start(1)
+	q.stop()
+	return ran, added
+}
+
+func TestSendQueueOrder(t *testing.T) {
+	ran, added := runQueue(5, queueBlock, 5)
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(ran, want) || !reflect.DeepEqual(added, want) {
+		t.Errorf("ran %v of %v enqueued, want %v", ran, added, want)
+	}
+}
+
+func TestSendQueueDropNewest(t *testing.T) {
+	ran, added := runQueue(2, queueDropNewest, 4)
+	if want := []int{1, 2}; !reflect.DeepEqual(ran, want) || !reflect.DeepEqual(added, want) {
+		t.Errorf("ran %v of %v enqueued, want %v", ran, added, want)
+	}
+}
+
+func TestSendQueueDropOldest(t *testing.T) {
+	ran, added := runQueue(2, queueDropOldest, 4)
+	if want := []int{3, 4}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(added, want) {
+		t.Errorf("enqueued %v, want %v", added, want)
+	}
+}
+
+func TestSendQueueBlock(t *testing.T) {
+	q := newSendQueue(1, queueBlock)
+	q.enqueue(func() {})
+
+	enqueued := make(chan bool)
+	go func() { enqueued <- q.enqueue(func() {}) }()
+	select {
+	case <-enqueued:
+		t.Fatal("enqueue on a full queue didn't block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	q.start(1)
+	select {
+	case ok := <-enqueued:
+		if !ok {
+			t.Error("blocked job was dropped")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("enqueue still blocked once a worker started")
+	}
+	q.stop()
+}
+
+func TestSendQueueStopped(t *testing.T) {
+	q := newSendQueue(1, queueBlock)
+	q.start(1)
+	q.stop()
+	if q.enqueue(func() {}) {
+		t.Error("job enqueued after the queue stopped")
+	}
+	q.stop()
+}
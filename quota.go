@@ -0,0 +1,156 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// defaultUserQuotaWindow is the window user quotas apply over if
+	// user_quota_window isn't set.
+	defaultUserQuotaWindow = time.Hour
+	// defaultQuotaTimeoutStrikes is how many times users may exceed their
+	// quota before being timed out, if quota_timeout_strikes isn't set.
+	defaultQuotaTimeoutStrikes = 3
+	// maxTimeout is the longest timeout in seconds Discord allows.
+	maxTimeout = 28 * 24 * 60 * 60
+)
+
+// quotaKey identifies a user in a guild.
+type quotaKey struct {
+	GuildID string
+	UserID  string
+}
+
+// quotaTracker records when users triggered commands in each guild, to limit
+// how many they trigger within a sliding window.
+type quotaTracker struct {
+	mu   sync.Mutex
+	uses map[quotaKey][]time.Time
+	// strikes counts how many times users have exceeded their quota since
+	// they were last timed out, until their uses expire.
+	strikes map[quotaKey]int
+}
+
+// newQuotaTracker returns an empty quotaTracker.
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{uses: make(map[quotaKey][]time.Time), strikes: make(map[quotaKey]int)}
+}
+
+// allow reports whether the user may trigger a command in the guild at now,
+// given that at most limit commands may be triggered within window. If so,
+// the use is recorded. A limit of zero or less allows every use.
+func (q *quotaTracker) allow(guildID, userID string, limit int, window time.Duration, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	key := quotaKey{GuildID: guildID, UserID: userID}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	uses := expireUses(q.uses[key], window, now)
+	if len(uses) >= limit {
+		q.uses[key] = uses
+		return false
+	}
+	q.uses[key] = append(uses, now)
+	return true
+}
+
+// strike records that the user exceeded their quota in the guild, and
+// reports whether they have now done so threshold times, in which case their
+// strikes are reset.
+func (q *quotaTracker) strike(guildID, userID string, threshold int) bool {
+	key := quotaKey{GuildID: guildID, UserID: userID}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.strikes[key]++
+	if q.strikes[key] < threshold {
+		return false
+	}
+	delete(q.strikes, key)
+	return true
+}
+
+// prune removes uses older than window at now, along with the strikes of
+// users with no remaining uses.
+func (q *quotaTracker) prune(window time.Duration, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for key, uses := range q.uses {
+		uses = expireUses(uses, window, now)
+		if len(uses) == 0 {
+			delete(q.uses, key)
+			delete(q.strikes, key)
+		} else {
+			q.uses[key] = uses
+		}
+	}
+}
+
+// expireUses returns uses, which are in order, without those older than
+// window at now.
+func expireUses(uses []time.Time, window time.Duration, now time.Time) []time.Time {
+	i := 0
+	for i < len(uses) && now.Sub(uses[i]) >= window {
+		i++
+	}
+	return uses[i:]
+}
+
+// userQuotaWindow returns the window user quotas apply over.
+func (c *Config) userQuotaWindow() time.Duration {
+	if c.UserQuotaWindow <= 0 {
+		return defaultUserQuotaWindow
+	}
+	return time.Duration(c.UserQuotaWindow) * time.Second
+}
+
+// quotaTimeoutStrikes returns how many times users may exceed their quota
+// before being timed out.
+func (c *Config) quotaTimeoutStrikes() int {
+	if c.QuotaTimeoutStrikes <= 0 {
+		return defaultQuotaTimeoutStrikes
+	}
+	return c.QuotaTimeoutStrikes
+}
+
+// checkQuota reports whether the user may trigger a command in the guild
+// now, and records the use if so. Admins are exempt. Users who repeatedly
+// exceed their quota are timed out, if enabled.
+func checkQuota(s Messenger, config *Config, guildID, userID string) bool {
+	if isAdmin(config, userID) {
+		return true
+	}
+	if Quotas.allow(guildID, userID, config.UserQuota, config.userQuotaWindow(), time.Now()) {
+		return true
+	}
+	if config.QuotaTimeout > 0 && guildID != "" && Quotas.strike(guildID, userID, config.quotaTimeoutStrikes()) {
+		timeoutMember(s, config, guildID, userID, time.Now())
+	}
+	return false
+}
+
+// timeoutMember times out the member for the configured duration from now.
+// The bot needs the moderate members permission.
+func timeoutMember(s Messenger, config *Config, guildID, userID string, now time.Time) {
+	ctx, cancel := requestContext(config)
+	defer cancel()
+
+	duration := time.Duration(config.QuotaTimeout) * time.Second
+	until := now.Add(duration)
+	err := s.GuildMemberTimeout(guildID, userID, &until, discordgo.WithContext(ctx))
+	if err != nil {
+		slog.Error("error timing out user over quota", "user_id", userID, "guild_id", guildID, "err", err)
+		return
+	}
+	slog.Info("timed out user over quota", "user_id", userID, "guild_id", guildID, "duration", duration)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestQuotaTrackerAllow(t *testing.T) {
+	q := newQuotaTracker()
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if !q.allow("10", "30", 2, time.Minute, now) {
+			t.Fatalf("use %d was refused, want allowed", i+1)
+		}
+	}
+	if q.allow("10", "30", 2, time.Minute, now) {
+		t.Error("third use within the window was allowed, want refused")
+	}
+	if !q.allow("11", "30", 2, time.Minute, now) {
+		t.Error("use in another guild was refused, want allowed")
+	}
+	if !q.allow("10", "30", 2, time.Minute, now.Add(time.Minute)) {
+		t.Error("use after the window was refused, want allowed")
+	}
+	if !q.allow("10", "30", 0, time.Minute, now) {
+		t.Error("use without a limit was refused, want allowed")
+	}
+}
+
+func TestQuotaTrackerStrike(t *testing.T) {
+	q := newQuotaTracker()
+
+	var got []bool
+	for i := 0; i < 7; i++ {
+		got = append(got, q.strike("10", "30", 3))
+	}
+	want := []bool{false, false, true, false, false, true, false}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("strikes = %v, want %v", got, want)
+	}
+	if q.strike("10", "31", 2) {
+		t.Error("another user's first strike triggered a timeout")
+	}
+}
+
+func TestQuotaTrackerPruneResetsStrikes(t *testing.T) {
+	q := newQuotaTracker()
+	now := time.Now()
+
+	q.allow("10", "30", 1, time.Minute, now)
+	q.strike("10", "30", 2)
+	q.prune(time.Minute, now.Add(time.Minute))
+	if q.strike("10", "30", 2) {
+		t.Error("strike after the uses expired triggered a timeout, want strikes reset")
+	}
+}
+
+func TestQuotaTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{name: "disabled", config: "prefix: \"!\"\nuser_quota: 1\ncommands:\n  ping:\n    output: Pong!\n"},
+		{name: "after strikes", config: "prefix: \"!\"\nuser_quota: 1\nquota_timeout: 60\nquota_timeout_strikes: 2\ncommands:\n  ping:\n    output: Pong!\n", want: []string{"30"}},
+		{name: "admin exempt", config: "prefix: \"!\"\nuser_quota: 1\nquota_timeout: 60\nquota_timeout_strikes: 2\nadmins: [\"30\"]\ncommands:\n  ping:\n    output: Pong!\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			var msgs []*discordgo.MessageCreate
+			for i := 0; i < 4; i++ {
+				msgs = append(msgs, testMessage("30", "!ping", false))
+			}
+			handleWith(t, fake, tt.config, msgs...)
+			if !reflect.DeepEqual(fake.timedOut, tt.want) {
+				t.Errorf("timed out %q, want %q", fake.timedOut, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rateLimitDrop drops messages that would exceed the rate limit.
+	rateLimitDrop = "drop"
+	// rateLimitQueue delays messages until the rate limit allows them.
+	rateLimitQueue = "queue"
+)
+
+// errRateLimited is returned when a message is dropped by the rate limiter.
+var errRateLimited = errors.New("message dropped by rate limiter")
+
+// setRateLimit updates limiter to allow the given number of messages per
+// second. Zero or less disables the limit.
+func setRateLimit(limiter *rate.Limiter, messagesPerSecond float64) {
+	if messagesPerSecond <= 0 {
+		limiter.SetLimit(rate.Inf)
+		return
+	}
+	limiter.SetLimit(rate.Limit(messagesPerSecond))
+	limiter.SetBurst(int(math.Max(1, math.Ceil(messagesPerSecond))))
+}
+
+// waitToSend blocks until limiter allows a message to be sent, or returns
+// errRateLimited if mode is "drop" and a message can't be sent right away.
+func waitToSend(ctx context.Context, limiter *rate.Limiter, mode string) error {
+	if mode == rateLimitQueue {
+		return limiter.Wait(ctx)
+	}
+	if !limiter.Allow() {
+		return errRateLimited
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSetRateLimitBurst(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	setRateLimit(limiter, 2)
+
+	// A burst of messages at the same instant only lets the bucket through.
+	// The limiter's clock is passed in explicitly, starting late enough
+	// that the bucket is full.
+	now := time.Now().Add(time.Hour)
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if limiter.AllowN(now, 1) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d messages of a burst, want 2", allowed)
+	}
+
+	// The bucket refills at the configured rate.
+	if limiter.AllowN(now.Add(400*time.Millisecond), 1) {
+		t.Error("message allowed before a token was refilled")
+	}
+	if !limiter.AllowN(now.Add(500*time.Millisecond), 1) {
+		t.Error("message not allowed after a token was refilled")
+	}
+}
+
+func TestSetRateLimitFractional(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	setRateLimit(limiter, 0.5)
+	if got := limiter.Burst(); got != 1 {
+		t.Errorf("burst = %d, want 1", got)
+	}
+	now := time.Now().Add(time.Hour)
+	if !limiter.AllowN(now, 1) {
+		t.Fatal("first message not allowed")
+	}
+	if limiter.AllowN(now.Add(time.Second), 1) {
+		t.Error("message allowed before a token was refilled")
+	}
+	if !limiter.AllowN(now.Add(2*time.Second), 1) {
+		t.Error("message not allowed after a token was refilled")
+	}
+}
+
+func TestSetRateLimitDisabled(t *testing.T) {
+	limiter := rate.NewLimiter(1, 1)
+	setRateLimit(limiter, 0)
+	now := time.Now().Add(time.Hour)
+	for i := 0; i < 100; i++ {
+		if !limiter.AllowN(now, 1) {
+			t.Fatalf("message %d not allowed with the limit disabled", i)
+		}
+	}
+}
+
+func TestWaitToSendDrop(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	ctx := context.Background()
+	if err := waitToSend(ctx, limiter, rateLimitDrop); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	if err := waitToSend(ctx, limiter, rateLimitDrop); !errors.Is(err, errRateLimited) {
+		t.Errorf("second message error = %v, want %v", err, errRateLimited)
+	}
+}
+
+func TestWaitToSendQueue(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	if err := waitToSend(context.Background(), limiter, rateLimitQueue); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+
+	// The second message would wait for an hour, so it gives up as soon
+	// as the context would expire first.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := waitToSend(ctx, limiter, rateLimitQueue); err == nil {
+		t.Error("queued message sent before the limit allowed it")
+	}
+}
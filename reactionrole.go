@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ReactionRoles maps message IDs to emoji to the IDs of the roles given to
+// users who react to the message with the emoji.
+type ReactionRoles map[string]map[string]string
+
+// reactionRoleKey identifies a reaction to a message.
+type reactionRoleKey struct {
+	MessageID string
+	Emoji     string
+}
+
+// buildReactionRoles returns the configured reaction roles keyed by message
+// and emoji, with emoji in the form used by the Discord API. Invalid emoji
+// are logged and skipped.
+func buildReactionRoles(config ReactionRoles) map[reactionRoleKey]string {
+	roles := make(map[reactionRoleKey]string)
+	for messageID, emojis := range config {
+		for emoji, roleID := range emojis {
+			parsed, err := parseEmoji(emoji)
+			if err != nil {
+				slog.Warn("invalid reaction role emoji; ignoring it", "message_id", messageID, "emoji", emoji, "err", err)
+				continue
+			}
+			roles[reactionRoleKey{MessageID: messageID, Emoji: parsed}] = roleID
+		}
+	}
+	return roles
+}
+
+// reactionRole returns the ID of the role given for reacting to the message
+// with the emoji, if there is one.
+func reactionRole(roles map[reactionRoleKey]string, messageID string, emoji discordgo.Emoji) (string, bool) {
+	roleID, ok := roles[reactionRoleKey{MessageID: messageID, Emoji: emoji.APIName()}]
+	return roleID, ok
+}
+
+// reactionRoleFor returns the ID of the role to give or take away for the
+// reaction, if there is one. Reaction roles only exist in guilds, and the
+// bot's own reactions are ignored.
+func reactionRoleFor(config *Config, botID string, r *discordgo.MessageReaction) (string, bool) {
+	if r.GuildID == "" || r.UserID == botID {
+		return "", false
+	}
+	return reactionRole(config.reactionRoles, r.MessageID, r.Emoji)
+}
+
+// validateReactionRoles returns all problems with the configured reaction
+// roles.
+func validateReactionRoles(config ReactionRoles) []error {
+	var errs []error
+
+	// Sort keys so errors are reported in the same order on every load.
+	messageIDs := make([]string, 0, len(config))
+	for messageID := range config {
+		messageIDs = append(messageIDs, messageID)
+	}
+	sort.Strings(messageIDs)
+
+	for _, messageID := range messageIDs {
+		emojis := make([]string, 0, len(config[messageID]))
+		for emoji := range config[messageID] {
+			emojis = append(emojis, emoji)
+		}
+		sort.Strings(emojis)
+
+		for _, emoji := range emojis {
+			if _, err := parseEmoji(emoji); err != nil {
+				errs = append(errs, fmt.Errorf("reaction_roles: message %q: %w", messageID, err))
+			}
+			if strings.TrimSpace(config[messageID][emoji]) == "" {
+				errs = append(errs, fmt.Errorf("reaction_roles: message %q: emoji %q: blank role ID", messageID, emoji))
+			}
+		}
+	}
+	return errs
+}
+
+func messageReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if Confirmations.deliver(r.MessageID, r.UserID, r.Emoji.APIName()) {
+		return
+	}
+	updateReactionRole(s, r.MessageReaction, true)
+}
+
+func messageReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	updateReactionRole(s, r.MessageReaction, false)
+}
+
+// updateReactionRole gives or takes away the role for the reaction, if there
+// is one.
+func updateReactionRole(s *discordgo.Session, r *discordgo.MessageReaction, add bool) {
+	config := CurrentConfig.Load()
+	roleID, ok := reactionRoleFor(config, s.State.User.ID, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := requestContext(config)
+	defer cancel()
+	var err error
+	if add {
+		err = s.GuildMemberRoleAdd(r.GuildID, r.UserID, roleID, discordgo.WithContext(ctx))
+	} else {
+		err = s.GuildMemberRoleRemove(r.GuildID, r.UserID, roleID, discordgo.WithContext(ctx))
+	}
+	if err != nil {
+		slog.Error("error updating reaction role", "user_id", r.UserID, "role_id", roleID, "add", add, "err", err)
+		return
+	}
+	slog.Info("reaction role updated", "user_id", r.UserID, "role_id", roleID, "add", add)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestReactionRole(t *testing.T) {
+	roles := buildReactionRoles(ReactionRoles{
+		"100": {"👍": "500", "<:party:123>": "501", "bad:": "502"},
+		"101": {"party:123": "503"},
+	})
+	if len(roles) != 3 {
+		t.Errorf("got %d reaction roles, want 3 with the invalid emoji skipped", len(roles))
+	}
+
+	tests := []struct {
+		name      string
+		messageID string
+		emoji     discordgo.Emoji
+		want      string
+	}{
+		{name: "unicode", messageID: "100", emoji: discordgo.Emoji{Name: "👍"}, want: "500"},
+		{name: "custom", messageID: "100", emoji: discordgo.Emoji{Name: "party", ID: "123"}, want: "501"},
+		{name: "custom by name and ID", messageID: "101", emoji: discordgo.Emoji{Name: "party", ID: "123"}, want: "503"},
+		{name: "other emoji", messageID: "100", emoji: discordgo.Emoji{Name: "👎"}},
+		{name: "other message", messageID: "102", emoji: discordgo.Emoji{Name: "👍"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reactionRole(roles, tt.messageID, tt.emoji)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("reactionRole(%q, %q) = %q, %t, want %q", tt.messageID, tt.emoji.APIName(), got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestReactionRoleFor(t *testing.T) {
+	config := &Config{reactionRoles: buildReactionRoles(ReactionRoles{"100": {"👍": "500"}})}
+	reaction := func(guildID, userID string) *discordgo.MessageReaction {
+		return &discordgo.MessageReaction{GuildID: guildID, UserID: userID, MessageID: "100", Emoji: discordgo.Emoji{Name: "👍"}}
+	}
+	tests := []struct {
+		name     string
+		reaction *discordgo.MessageReaction
+		want     string
+	}{
+		{name: "member", reaction: reaction("10", "30"), want: "500"},
+		{name: "bot", reaction: reaction("10", "1")},
+		{name: "DM", reaction: reaction("", "30")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := reactionRoleFor(config, "1", tt.reaction)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("reactionRoleFor = %q, %t, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
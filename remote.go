@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// remoteCommands fetches commands from an HTTP endpoint serving them as JSON,
+// caching them so that unchanged commands aren't downloaded again.
+type remoteCommands struct {
+	url    string
+	client *http.Client
+
+	mu sync.Mutex
+	// etag and lastModified are the validators of the cached commands, sent
+	// so the server can reply that they haven't changed.
+	etag         string
+	lastModified string
+	commands     map[string]CommandConfig
+}
+
+// newRemoteCommands returns a remoteCommands fetching from url.
+func newRemoteCommands(url string) *remoteCommands {
+	return &remoteCommands{url: url, client: http.DefaultClient}
+}
+
+// fetch fetches the commands, unless they haven't changed since they were
+// last fetched, and reports whether they changed.
+func (r *remoteCommands) fetch(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.commands != nil {
+		if r.etag != "" {
+			req.Header.Set("If-None-Match", r.etag)
+		}
+		if r.lastModified != "" {
+			req.Header.Set("If-Modified-Since", r.lastModified)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && r.commands != nil:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	var commands map[string]CommandConfig
+	err = decodeConfig("commands.json", body, &commands)
+	if err != nil {
+		return false, err
+	}
+	if commands == nil {
+		commands = make(map[string]CommandConfig)
+	}
+
+	r.commands = commands
+	r.etag = resp.Header.Get("ETag")
+	r.lastModified = resp.Header.Get("Last-Modified")
+	return true, nil
+}
+
+// load fetches the commands and returns them. If fetching fails, the
+// commands last fetched are returned along with the error, if there are any.
+func (r *remoteCommands) load(ctx context.Context) (map[string]CommandConfig, error) {
+	_, err := r.fetch(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commands, err
+}
+
+// refreshCommands fetches the commands every interval, calling reload
+// whenever they change, until ctx is done.
+func refreshCommands(ctx context.Context, r *remoteCommands, interval time.Duration, reload func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fetchCtx, cancel := requestContext(CurrentConfig.Load())
+			changed, err := r.fetch(fetchCtx)
+			cancel()
+			if err != nil {
+				slog.Error("error refreshing commands", "url", r.url, "err", err)
+				continue
+			}
+			if changed {
+				slog.Info("commands changed; reloading config", "url", r.url)
+				reload()
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// commandsServer returns a server serving the commands as JSON with an
+// ETag, replying that they haven't changed when the client has them. If
+// fail is set, it replies with an error instead.
+func commandsServer(t *testing.T, fail *atomic.Bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail != nil && fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ping": {"output": "Pong!"}, "hello": {"output": ["Hi", "Hey"]}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &notModified
+}
+
+func TestRemoteCommandsFetch(t *testing.T) {
+	srv, notModified := commandsServer(t, nil)
+	r := newRemoteCommands(srv.URL)
+
+	changed, err := r.fetch(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("first fetch = %v, %v, want changed", changed, err)
+	}
+	if len(r.commands) != 2 || r.commands["ping"].Output[0].Text != "Pong!" || len(r.commands["hello"].Output) != 2 {
+		t.Fatalf("commands = %+v, want ping and hello", r.commands)
+	}
+
+	changed, err = r.fetch(context.Background())
+	if err != nil || changed {
+		t.Fatalf("second fetch = %v, %v, want unchanged", changed, err)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("server replied not modified %d times, want 1", notModified.Load())
+	}
+	if r.commands["ping"].Output[0].Text != "Pong!" {
+		t.Errorf("commands after not modified = %+v, want cache kept", r.commands)
+	}
+}
+
+func TestRemoteCommandsLoadKeepsCache(t *testing.T) {
+	var fail atomic.Bool
+	srv, _ := commandsServer(t, &fail)
+	r := newRemoteCommands(srv.URL)
+
+	fail.Store(true)
+	commands, err := r.load(context.Background())
+	if err == nil || commands != nil {
+		t.Fatalf("load before any fetch = %v, %v, want an error and no commands", commands, err)
+	}
+
+	fail.Store(false)
+	if _, err := r.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fail.Store(true)
+	commands, err = r.load(context.Background())
+	if err == nil {
+		t.Error("load from a failing server succeeded, want an error")
+	}
+	if len(commands) != 2 {
+		t.Errorf("commands = %+v, want the cached commands", commands)
+	}
+}
+
+func TestLoadConfigRemote(t *testing.T) {
+	var fail atomic.Bool
+	srv, _ := commandsServer(t, &fail)
+	Remote = newRemoteCommands(srv.URL)
+	t.Cleanup(func() { Remote = nil })
+
+	config := useConfig(t, "prefix: \"!\"\n")
+	if _, ok := config.Commands["ping"]; !ok {
+		t.Fatalf("commands = %+v, want the fetched commands", config.Commands)
+	}
+
+	// Reloading while the server fails keeps the commands last fetched.
+	fail.Store(true)
+	loadConfig()
+	if _, ok := CurrentConfig.Load().Commands["ping"]; !ok {
+		t.Errorf("commands after a failed reload = %+v, want the previous commands", CurrentConfig.Load().Commands)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"text/template"
+	"text/template/parse"
+)
+
+// responseTemplate is a parsed response template. Templates without actions
+// render the same for every message, so they are rendered once when parsed.
+type responseTemplate struct {
+	tmpl *template.Template
+	// static is the rendered template, if it is static.
+	static *string
+}
+
+// parseResponseTemplate parses text as a response template, and renders it
+// if it is static.
+func parseResponseTemplate(name, text string) (*responseTemplate, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &responseTemplate{tmpl: tmpl}
+	if isStaticTemplate(tmpl) {
+		rendered, err := render(tmpl, TemplateData{})
+		if err != nil {
+			return nil, err
+		}
+		t.static = &rendered
+	}
+	return t, nil
+}
+
+// render renders the template with the given data, or returns it as
+// rendered when parsed if it is static.
+func (t *responseTemplate) render(data TemplateData) (string, error) {
+	if t.static != nil {
+		return *t.static, nil
+	}
+	return render(t.tmpl, data)
+}
+
+// isStaticTemplate reports whether tmpl renders the same regardless of the
+// data it is executed with, which is the case if it contains only text.
+func isStaticTemplate(tmpl *template.Template) bool {
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return true
+	}
+	for _, node := range tmpl.Tree.Root.Nodes {
+		if node.Type() != parse.NodeText {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+)
+
+// countingTemplate returns a template that counts how many times it is
+// executed in *n.
+func countingTemplate(t *testing.T, n *int) *template.Template {
+	t.Helper()
+	tmpl, err := template.New("spy").Funcs(template.FuncMap{"count": func() string {
+		*n++
+		return ""
+	}}).Parse("{{count}}{{.User}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
+
+func TestIsStaticTemplate(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "", want: true},
+		{text: "Hello, world", want: true},
+		{text: "{{/* a comment */}}Hello", want: true},
+		{text: "Hello, {{.User}}", want: false},
+		{text: `{{"constant"}}`, want: false},
+		{text: "{{if .Args}}args{{end}}", want: false},
+	}
+	for _, tt := range tests {
+		tmpl, err := template.New("t").Parse(tt.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isStaticTemplate(tmpl); got != tt.want {
+			t.Errorf("isStaticTemplate(%q) = %t, want %t", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestResponseTemplateRendersStaticOnce(t *testing.T) {
+	rt, err := parseResponseTemplate("ping", "pong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	renders := 0
+	rt.tmpl = countingTemplate(t, &renders)
+	for i := 0; i < 3; i++ {
+		got, err := rt.render(TemplateData{User: "user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "pong" {
+			t.Errorf("render = %q, want %q", got, "pong")
+		}
+	}
+	if renders != 0 {
+		t.Errorf("static template rendered %d times after parsing, want 0", renders)
+	}
+}
+
+func TestResponseTemplateRendersDynamicEachTime(t *testing.T) {
+	rt, err := parseResponseTemplate("hi", "Hi {{.User}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.static != nil {
+		t.Fatal("dynamic template was rendered when parsed")
+	}
+	renders := 0
+	rt.tmpl = countingTemplate(t, &renders)
+	for _, user := range []string{"a", "b", "c"} {
+		got, err := rt.render(TemplateData{User: user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != user {
+			t.Errorf("render = %q, want %q", got, user)
+		}
+	}
+	if renders != 3 {
+		t.Errorf("dynamic template rendered %d times, want 3", renders)
+	}
+}
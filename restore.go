@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// minRestoreInterval is the shortest interval between restoring state after
+// reconnects, so that a flapping connection doesn't cause a burst of API
+// calls.
+const minRestoreInterval = time.Minute
+
+// Restores limits how often state is restored after reconnects.
+var Restores = &restoreGuard{interval: minRestoreInterval}
+
+// restoreGuard allows an action at most once per interval.
+type restoreGuard struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// allow reports whether the action may run at now, and records it if so.
+func (g *restoreGuard) allow(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.last.IsZero() && now.Sub(g.last) < g.interval {
+		return false
+	}
+	g.last = now
+	return true
+}
+
+// restoreState re-applies state that may be lost on reconnecting: the bot's
+// status, and its slash commands, which are only registered again if any are
+// missing.
+func restoreState(s *discordgo.Session, config *Config) {
+	if !Restores.allow(time.Now()) {
+		slog.Debug("state restored recently; skipping")
+		return
+	}
+
+	if PresenceRotator != nil {
+		PresenceRotator.start(config.Presence, config.presenceInterval())
+	}
+
+	if !config.SlashCommands {
+		return
+	}
+	ctx, cancel := requestContext(config)
+	registered, err := s.ApplicationCommands(s.State.User.ID, "", discordgo.WithContext(ctx))
+	cancel()
+	if err != nil {
+		slog.Error("error checking slash commands", "err", err)
+		return
+	}
+	if !slashCommandsMissing(registered, slashCommands(config)) {
+		return
+	}
+	slog.Info("slash commands missing after reconnect; registering them again")
+	err = registerSlashCommands(s, config)
+	if err != nil {
+		slog.Error("error registering slash commands", "err", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestRestoreGuard(t *testing.T) {
+	g := &restoreGuard{interval: time.Minute}
+	start := time.Now()
+	tests := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{at: 0, want: true},
+		{at: time.Second},
+		{at: 59 * time.Second},
+		{at: time.Minute, want: true},
+		{at: 90 * time.Second},
+		{at: 3 * time.Minute, want: true},
+	}
+	for _, tt := range tests {
+		if got := g.allow(start.Add(tt.at)); got != tt.want {
+			t.Errorf("allow at %v = %t, want %t", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestSlashCommandsMissing(t *testing.T) {
+	commands := func(names ...string) []*discordgo.ApplicationCommand {
+		var cmds []*discordgo.ApplicationCommand
+		for _, name := range names {
+			cmds = append(cmds, &discordgo.ApplicationCommand{Name: name})
+		}
+		return cmds
+	}
+	tests := []struct {
+		name       string
+		registered []*discordgo.ApplicationCommand
+		want       []*discordgo.ApplicationCommand
+		missing    bool
+	}{
+		{name: "all registered", registered: commands("ping", "help"), want: commands("help", "ping")},
+		{name: "extra registered", registered: commands("ping", "old"), want: commands("ping")},
+		{name: "one missing", registered: commands("ping"), want: commands("ping", "help"), missing: true},
+		{name: "none registered", want: commands("ping"), missing: true},
+		{name: "none wanted"},
+	}
+	for _, tt := range tests {
+		if got := slashCommandsMissing(tt.registered, tt.want); got != tt.missing {
+			t.Errorf("%s: slashCommandsMissing = %t, want %t", tt.name, got, tt.missing)
+		}
+	}
+}
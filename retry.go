@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// sendRetries is how many times a send that fails with a retryable error
+	// is retried.
+	sendRetries = 3
+	// sendRetryDelay is the delay before the first retry, if the error
+	// doesn't say how long to wait.
+	sendRetryDelay = 500 * time.Millisecond
+)
+
+// retryDelay returns how long to wait before the given retry, counting from
+// 1, of a call that failed with err, and reports whether the call should be
+// retried at all. Rate limits and server errors are retried.
+func retryDelay(err error, retry int) (time.Duration, bool) {
+	var rateLimited *discordgo.RateLimitError
+	if errors.As(err, &rateLimited) && rateLimited.RateLimit != nil && rateLimited.TooManyRequests != nil {
+		return rateLimited.RetryAfter, true
+	}
+
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		return 0, false
+	}
+	status := restErr.Response.StatusCode
+	if status != http.StatusTooManyRequests && status < http.StatusInternalServerError {
+		return 0, false
+	}
+	return sendRetryDelay << (retry - 1), true
+}
+
+// withRetry calls call until it succeeds or fails with an error that isn't
+// retryable, retrying up to sendRetries times. It stops waiting to retry
+// once ctx is done.
+func withRetry(ctx context.Context, call func() error) error {
+	for retry := 1; ; retry++ {
+		err := call()
+		if err == nil || retry > sendRetries {
+			return err
+		}
+		delay, ok := retryDelay(err, retry)
+		if !ok {
+			return err
+		}
+		slog.Warn("error sending message; retrying", "retry", retry, "delay", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// restError returns an error for a response with the given status.
+func restError(status int) error {
+	return &discordgo.RESTError{Response: &http.Response{StatusCode: status}}
+}
+
+// rateLimitError returns a rate limit error asking to retry after d.
+func rateLimitError(d time.Duration) error {
+	return &discordgo.RateLimitError{RateLimit: &discordgo.RateLimit{TooManyRequests: &discordgo.TooManyRequests{RetryAfter: d}}}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retry     int
+		want      time.Duration
+		wantRetry bool
+	}{
+		{name: "rate limited", err: rateLimitError(3 * time.Second), retry: 1, want: 3 * time.Second, wantRetry: true},
+		{name: "too many requests", err: restError(http.StatusTooManyRequests), retry: 1, want: sendRetryDelay, wantRetry: true},
+		{name: "server error", err: restError(http.StatusBadGateway), retry: 1, want: sendRetryDelay, wantRetry: true},
+		{name: "backoff", err: restError(http.StatusServiceUnavailable), retry: 3, want: 4 * sendRetryDelay, wantRetry: true},
+		{name: "client error", err: restError(http.StatusForbidden), retry: 1},
+		{name: "other error", err: errors.New("connection reset"), retry: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryDelay(tt.err, tt.retry)
+			if got != tt.want || ok != tt.wantRetry {
+				t.Errorf("retryDelay = %v, %t, want %v, %t", got, ok, tt.want, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	m := testMessage("30", "!ping", false)
+	tests := []struct {
+		name     string
+		sendErrs []error
+		wantErr  bool
+		wantSent int
+	}{
+		{name: "eventually delivered", sendErrs: []error{rateLimitError(time.Millisecond), rateLimitError(time.Millisecond)}, wantSent: 1},
+		{name: "not retryable", sendErrs: []error{restError(http.StatusForbidden)}, wantErr: true},
+		{
+			name:     "gives up",
+			sendErrs: []error{rateLimitError(time.Millisecond), rateLimitError(time.Millisecond), rateLimitError(time.Millisecond), rateLimitError(time.Millisecond)},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{sendErrs: tt.sendErrs}
+			config := &Config{}
+			CurrentConfig.Store(config)
+			err := sendResponse(fake, m, config, &discordgo.MessageSend{Content: "pong"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sendResponse error = %v, want error %t", err, tt.wantErr)
+			}
+			if sent := fake.messages(); len(sent) != tt.wantSent {
+				t.Errorf("sent %d messages, want %d", len(sent), tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := withRetry(ctx, func() error {
+		calls++
+		return rateLimitError(time.Hour)
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("withRetry = %v after %d calls, want an error after 1", err, calls)
+	}
+}
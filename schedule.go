@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule defines a message sent on a schedule.
+type Schedule struct {
+	// Cron is a standard cron expression, such as "0 * * * *" for hourly.
+	Cron string `yaml:"cron"`
+	// Channel is the ID of the channel to send the message to.
+	Channel string `yaml:"channel"`
+	// Message is the message to send.
+	Message string `yaml:"message"`
+}
+
+// scheduleLocation returns the location named by timezone, an IANA name such
+// as "America/New_York", that schedules run in. It returns the local time
+// zone if timezone is empty, and UTC if it is invalid.
+func scheduleLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		slog.Warn("invalid timezone; using UTC", "timezone", timezone, "err", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// scheduler sends scheduled messages.
+type scheduler struct {
+	mu      sync.Mutex
+	cron    *cron.Cron
+	send    func(channelID, message string)
+	stopped bool
+}
+
+// newScheduler returns a scheduler that sends messages using send.
+func newScheduler(send func(channelID, message string)) *scheduler {
+	return &scheduler{send: send}
+}
+
+// start replaces any running schedules with the given ones, run in the given
+// location. Schedules with invalid cron expressions are logged and skipped.
+// It does nothing once the scheduler has been stopped.
+func (sc *scheduler) start(schedules []Schedule, loc *time.Location) {
+	c := cron.New(cron.WithLocation(loc))
+	for _, schedule := range schedules {
+		schedule := schedule
+		_, err := c.AddFunc(schedule.Cron, func() {
+			sc.send(schedule.Channel, schedule.Message)
+		})
+		if err != nil {
+			slog.Warn("invalid schedule; skipping it", "cron", schedule.Cron, "err", err)
+		}
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.stopped {
+		return
+	}
+	if sc.cron != nil {
+		sc.cron.Stop()
+	}
+	sc.cron = c
+	sc.cron.Start()
+}
+
+// stop stops all schedules, waiting for any running sends to finish.
+func (sc *scheduler) stop() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.stopped = true
+	if sc.cron != nil {
+		<-sc.cron.Stop().Done()
+		sc.cron = nil
+	}
+}
+
+// sendScheduled sends a scheduled message to the channel, split into
+// multiple messages if it is too long.
+func sendScheduled(s Messenger, channelID, message string) {
+	config := CurrentConfig.Load()
+	for _, chunk := range splitMessage(message, maxMessageLength) {
+		err := waitToSend(context.Background(), Limiter, config.RateLimitMode)
+		if err == nil {
+			_, err = sendMessage(s, config, channelID, &discordgo.MessageSend{Content: chunk})
+		}
+		if err != nil {
+			slog.Error("error sending scheduled message", "channel_id", channelID, "err", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerSkipsInvalidSchedules(t *testing.T) {
+	sc := newScheduler(func(channelID, message string) {})
+	defer sc.stop()
+	sc.start([]Schedule{
+		{Cron: "0 * * * *", Channel: "1", Message: "hourly"},
+		{Cron: "not a cron expression", Channel: "2", Message: "never"},
+		{Cron: "0 0 * * 7 *", Channel: "3", Message: "too many fields"},
+		{Cron: "@daily", Channel: "4", Message: "daily"},
+	}, time.UTC)
+
+	if got := len(sc.cron.Entries()); got != 2 {
+		t.Errorf("got %d schedules, want 2", got)
+	}
+}
+
+func TestSchedulerSendsDueSchedule(t *testing.T) {
+	type sent struct{ channelID, message string }
+	var mu sync.Mutex
+	var got []sent
+	sc := newScheduler(func(channelID, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, sent{channelID, message})
+	})
+	defer sc.stop()
+	sc.start([]Schedule{{Cron: "30 9 * * *", Channel: "1", Message: "Good morning"}}, time.UTC)
+
+	entries := sc.cron.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d schedules, want 1", len(entries))
+	}
+	from := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	if next, want := entries[0].Schedule.Next(from), time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("next run = %v, want %v", next, want)
+	}
+
+	// Run the schedule as the cron would once it is due.
+	entries[0].Job.Run()
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []sent{{"1", "Good morning"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+}
+
+func TestSchedulerRestartReplacesSchedules(t *testing.T) {
+	sc := newScheduler(func(channelID, message string) {})
+	sc.start([]Schedule{{Cron: "@hourly", Channel: "1", Message: "a"}}, time.UTC)
+	sc.start([]Schedule{{Cron: "@hourly", Channel: "1", Message: "a"}, {Cron: "@daily", Channel: "2", Message: "b"}}, time.UTC)
+	if got := len(sc.cron.Entries()); got != 2 {
+		t.Errorf("got %d schedules after restart, want 2", got)
+	}
+
+	// Once stopped, the scheduler stays stopped.
+	sc.stop()
+	sc.start([]Schedule{{Cron: "@hourly", Channel: "1", Message: "a"}}, time.UTC)
+	if sc.cron != nil {
+		t.Error("scheduler started after it was stopped")
+	}
+}
+
+func TestScheduleLocation(t *testing.T) {
+	if got := scheduleLocation(""); got != time.Local {
+		t.Errorf("scheduleLocation(\"\") = %v, want local", got)
+	}
+	if got := scheduleLocation("Not/AZone"); got != time.UTC {
+		t.Errorf("scheduleLocation of an invalid zone = %v, want UTC", got)
+	}
+	if got := scheduleLocation("America/New_York"); got.String() != "America/New_York" {
+		t.Errorf("scheduleLocation(\"America/New_York\") = %v", got)
+	}
+}
+
+func TestScheduleNextRunInLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data unavailable:", err)
+	}
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want time.Time
+	}{
+		// 9am in New York is 1pm UTC in summer and 2pm UTC in winter.
+		{name: "new york", loc: newYork, want: time.Date(2024, 7, 1, 13, 0, 0, 0, time.UTC)},
+		{name: "utc", loc: time.UTC, want: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "invalid", loc: scheduleLocation("Mars/Olympus_Mons"), want: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newScheduler(func(channelID, message string) {})
+			defer sc.stop()
+			sc.start([]Schedule{{Cron: "0 9 * * *", Channel: "1", Message: "Good morning"}}, tt.loc)
+
+			// The cron runs schedules from the current time in its location.
+			from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC).In(sc.cron.Location())
+			if next := sc.cron.Entries()[0].Schedule.Next(from); !next.Equal(tt.want) {
+				t.Errorf("next run = %v, want %v", next.UTC(), tt.want)
+			}
+		})
+	}
+
+	// Daylight saving time ends in November.
+	sc := newScheduler(func(channelID, message string) {})
+	defer sc.stop()
+	sc.start([]Schedule{{Cron: "0 9 * * *", Channel: "1", Message: "Good morning"}}, newYork)
+	from := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC).In(sc.cron.Location())
+	if next, want := sc.cron.Entries()[0].Schedule.Next(from), time.Date(2024, 12, 1, 14, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("next run in winter = %v, want %v", next.UTC(), want)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultRequestTimeout is the longest in seconds a call to the Discord API
+// may take if request_timeout isn't set. Zero disables the timeout.
+const defaultRequestTimeout = 10
+
+// errChannelNotAllowed is returned when sending to a channel the bot may not
+// send in.
+var errChannelNotAllowed = errors.New("channel is not in allowed_channels")
+
+// sendMessage sends data to the channel, unless the bot may not send in it.
+// Every message the bot sends to a channel goes through sendMessage, so that
+// allowed_channels can't be bypassed.
+func sendMessage(s Messenger, config *Config, channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	if !canSendTo(s, config, channelID, options...) {
+		return nil, errChannelNotAllowed
+	}
+	return s.ChannelMessageSendComplex(channelID, data, options...)
+}
+
+// canSendTo reports whether the bot may send in the channel. Threads may be
+// sent in if their parent channel may be.
+func canSendTo(s Messenger, config *Config, channelID string, options ...discordgo.RequestOption) bool {
+	if canSendIn(config, channelID) {
+		return true
+	}
+	channel, err := s.Channel(channelID, options...)
+	if err != nil || !channel.IsThread() {
+		return false
+	}
+	return canSendIn(config, channel.ParentID)
+}
+
+// sendResponse sends a response to the message m. Text responses are split
+// into multiple messages if they are too long, with any files attached to the
+// first.
+func sendResponse(s Messenger, m *discordgo.MessageCreate, config *Config, response *discordgo.MessageSend) error {
+	return sendResponseTo(s, m, m.ChannelID, config, response)
+}
+
+// sendResponseTo sends a response to the message m to the given channel,
+// which may not be the channel of m. Responses are only sent as replies in
+// the channel of m.
+func sendResponseTo(s Messenger, m *discordgo.MessageCreate, channelID string, config *Config, response *discordgo.MessageSend) error {
+	reply := config.Reply && channelID == m.ChannelID
+
+	// Embeds are sent as is.
+	if len(response.Embeds) > 0 {
+		return send(s, m, channelID, response, reply)
+	}
+
+	for i, chunk := range responseChunks(config, response) {
+		data := &discordgo.MessageSend{Content: chunk}
+		if i == 0 {
+			data.Files = response.Files
+			data.Components = response.Components
+		}
+		err := send(s, m, channelID, data, reply && i == 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send sends data to the given channel, optionally as a reply to the message
+// m. If replying fails, for example because m was deleted, data is sent to
+// the channel without the reply instead.
+func send(s Messenger, m *discordgo.MessageCreate, channelID string, data *discordgo.MessageSend, reply bool) error {
+	config := CurrentConfig.Load()
+	ctx, cancel := requestContext(config)
+	defer cancel()
+
+	// Wait for the rate limiter.
+	err := waitToSend(ctx, Limiter, config.RateLimitMode)
+	if err != nil {
+		return err
+	}
+
+	// Retry transient failures, rewinding any files that were read by the
+	// failed attempt. Each attempt is timed.
+	sendOnce := func() error {
+		err := SendLatency.time(func() error {
+			_, err := sendMessage(s, config, channelID, data, discordgo.WithContext(ctx))
+			return err
+		})
+		if err != nil {
+			rewindFiles(data.Files)
+		}
+		return err
+	}
+
+	if !reply {
+		return withRetry(ctx, sendOnce)
+	}
+
+	data.Reference = m.Reference()
+	err = withRetry(ctx, sendOnce)
+	if err == nil || ctx.Err() != nil || errors.Is(err, errChannelNotAllowed) {
+		return err
+	}
+	slog.Warn("error replying to message; sending without reply", "err", err)
+
+	data.Reference = nil
+	return withRetry(ctx, sendOnce)
+}
+
+// requestContext returns a context for calls to the Discord API that is
+// cancelled once the configured request timeout has passed, if one is set.
+func requestContext(config *Config) (context.Context, context.CancelFunc) {
+	if config.RequestTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), config.requestTimeout())
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSendMessageAllowedChannels(t *testing.T) {
+	config := &Config{AllowedChannels: []string{"20"}}
+	tests := []struct {
+		name      string
+		channelID string
+		wantErr   error
+	}{
+		{name: "allowed", channelID: "20"},
+		{name: "not allowed", channelID: "21", wantErr: errChannelNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			_, err := sendMessage(fake, config, tt.channelID, &discordgo.MessageSend{Content: "hi"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("sendMessage error = %v, want %v", err, tt.wantErr)
+			}
+			if sent := len(fake.messages()) > 0; sent != (tt.wantErr == nil) {
+				t.Errorf("sent = %v, want %v", sent, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestExecuteWebhookAllowedChannels(t *testing.T) {
+	hook := &Webhook{ID: "50", Token: "token"}
+	tests := []struct {
+		name    string
+		allowed []string
+		wantErr error
+	}{
+		{name: "no allowed channels"},
+		{name: "allowed", allowed: []string{"webhook-channel-50"}},
+		{name: "not allowed", allowed: []string{"20"}, wantErr: errChannelNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			config := &Config{AllowedChannels: tt.allowed}
+			err := executeWebhook(fake, config, hook, &discordgo.WebhookParams{Content: "hi"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("executeWebhook error = %v, want %v", err, tt.wantErr)
+			}
+			if sent := len(fake.messages()) > 0; sent != (tt.wantErr == nil) {
+				t.Errorf("sent = %v, want %v", sent, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestAuditAllowedChannels(t *testing.T) {
+	fake := &fakeMessenger{}
+	config := &Config{AuditChannel: "40", AllowedChannels: []string{"20"}}
+	audit(fake, config, &discordgo.User{ID: "30", Username: "user"}, "ping", "20")
+	if sent := fake.messages(); len(sent) != 0 {
+		t.Errorf("audit sent %v to a channel that isn't allowed", sent)
+	}
+}
+
+func TestSendResponseReply(t *testing.T) {
+	m := testMessage("30", "!ping", false)
+	tests := []struct {
+		name     string
+		reply    bool
+		sendErrs []error
+		want     *discordgo.MessageReference
+	}{
+		{name: "off"},
+		{name: "on", reply: true, want: m.Reference()},
+		{name: "reply fails", reply: true, sendErrs: []error{errors.New("unknown message")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{sendErrs: tt.sendErrs}
+			config := &Config{Reply: tt.reply}
+			CurrentConfig.Store(config)
+			if err := sendResponse(fake, m, config, &discordgo.MessageSend{Content: "pong"}); err != nil {
+				t.Fatalf("sendResponse: %v", err)
+			}
+			sent := fake.messages()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+			if !reflect.DeepEqual(sent[0].Reference, tt.want) {
+				t.Errorf("reference = %+v, want %+v", sent[0].Reference, tt.want)
+			}
+		})
+	}
+}
+
+// blockingMessenger is a fakeMessenger whose sends hang until their request
+// is cancelled.
+type blockingMessenger struct {
+	*fakeMessenger
+}
+
+func (b blockingMessenger) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error) {
+	req, err := http.NewRequest(http.MethodPost, "https://discord.com/api/channels/"+channelID+"/messages", nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &discordgo.RequestConfig{Request: req}
+	for _, option := range options {
+		option(cfg)
+	}
+	<-cfg.Request.Context().Done()
+	return nil, cfg.Request.Context().Err()
+}
+
+func TestSendResponseTimeout(t *testing.T) {
+	m := testMessage("30", "!ping", false)
+	config := &Config{RequestTimeout: 1}
+	CurrentConfig.Store(config)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sendResponse(blockingMessenger{&fakeMessenger{}}, m, config, &discordgo.MessageSend{Content: "pong"})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("sendResponse error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("sendResponse hung past the request timeout")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// applyShardEnv overrides the config's shard settings with the SHARD_ID and
+// SHARD_COUNT environment variables, if they are set.
+func applyShardEnv(config *Config, getenv func(string) string) error {
+	for _, v := range []struct {
+		name  string
+		value *int
+	}{
+		{"SHARD_ID", &config.ShardID},
+		{"SHARD_COUNT", &config.ShardCount},
+	} {
+		s := getenv(v.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%s %q: must be an integer", v.name, s)
+		}
+		*v.value = n
+	}
+	return nil
+}
+
+// validateShards returns an error if the shard settings are invalid. A shard
+// count of zero means the bot is not sharded.
+func validateShards(id, count int) error {
+	switch {
+	case count < 0:
+		return fmt.Errorf("shard_count %d: must not be negative", count)
+	case count == 0 && id != 0:
+		return fmt.Errorf("shard_id %d: shard_count must be set", id)
+	case count > 0 && (id < 0 || id >= count):
+		return fmt.Errorf("shard_id %d: must be at least 0 and less than shard_count %d", id, count)
+	}
+	return nil
+}
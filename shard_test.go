@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestApplyShardEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		config    Config
+		wantID    int
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "unset"},
+		{name: "config", config: Config{ShardID: 1, ShardCount: 2}, wantID: 1, wantCount: 2},
+		{name: "env", env: map[string]string{"SHARD_ID": "3", "SHARD_COUNT": "4"}, wantID: 3, wantCount: 4},
+		{
+			name:      "env overrides config",
+			env:       map[string]string{"SHARD_ID": "2"},
+			config:    Config{ShardID: 1, ShardCount: 4},
+			wantID:    2,
+			wantCount: 4,
+		},
+		{name: "not a number", env: map[string]string{"SHARD_COUNT": "two"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			err := applyShardEnv(&config, func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyShardEnv error = %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.ShardID != tt.wantID || config.ShardCount != tt.wantCount {
+				t.Errorf("shard %d of %d, want %d of %d", config.ShardID, config.ShardCount, tt.wantID, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestValidateShards(t *testing.T) {
+	tests := []struct {
+		id, count int
+		wantErr   bool
+	}{
+		{id: 0, count: 0},
+		{id: 0, count: 1},
+		{id: 3, count: 4},
+		{id: 4, count: 4, wantErr: true},
+		{id: -1, count: 4, wantErr: true},
+		{id: 1, count: 0, wantErr: true},
+		{id: 0, count: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateShards(tt.id, tt.count); (err != nil) != tt.wantErr {
+			t.Errorf("validateShards(%d, %d) = %v, want error %t", tt.id, tt.count, err, tt.wantErr)
+		}
+	}
+}
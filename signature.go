@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/bwmarrin/discordgo"
+)
+
+// responseChunks returns the text of response split into chunks that each
+// fit in a message, with the configured prefix added to the first chunk and
+// the suffix to the last. Text too long to fit in one message along with the
+// prefix and suffix is split like any long response rather than truncated,
+// so the prefix and suffix are always sent whole. Responses with embeds are
+// sent as one message with their text as it is, and responses with files
+// are left unsigned.
+func responseChunks(config *Config, response *discordgo.MessageSend) []string {
+	if len(response.Embeds) > 0 {
+		return []string{response.Content}
+	}
+	prefix, suffix := config.ResponsePrefix, config.ResponseSuffix
+	if response.Content == "" || len(response.Files) > 0 {
+		prefix, suffix = "", ""
+	}
+	return signChunks(splitMessage(response.Content, maxMessageLength-len(prefix)-len(suffix)), prefix, suffix)
+}
+
+// signChunks adds prefix to the first of chunks and suffix to the last.
+func signChunks(chunks []string, prefix, suffix string) []string {
+	chunks[0] = prefix + chunks[0]
+	chunks[len(chunks)-1] += suffix
+	return chunks
+}
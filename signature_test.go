@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestResponseChunks(t *testing.T) {
+	config := &Config{ResponsePrefix: "[bot] ", ResponseSuffix: " -- bot"}
+	long := strings.Repeat("word ", 1000)
+
+	chunks := responseChunks(config, &discordgo.MessageSend{Content: long})
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the text split", len(chunks))
+	}
+	if !strings.HasPrefix(chunks[0], config.ResponsePrefix) {
+		t.Errorf("first chunk %q doesn't start with the prefix", chunks[0][:20])
+	}
+	if !strings.HasSuffix(chunks[len(chunks)-1], config.ResponseSuffix) {
+		t.Errorf("last chunk doesn't end with the suffix")
+	}
+	var text string
+	for i, chunk := range chunks {
+		if len(chunk) > maxMessageLength {
+			t.Errorf("chunk %d is %d bytes, want at most %d", i, len(chunk), maxMessageLength)
+		}
+		text += chunk
+	}
+	if got, want := strings.Count(text, "word"), 1000; got != want {
+		t.Errorf("chunks hold %d words, want %d", got, want)
+	}
+
+	if got := responseChunks(config, &discordgo.MessageSend{Content: "hi"}); len(got) != 1 || got[0] != "[bot] hi -- bot" {
+		t.Errorf("short response = %q, want %q", got, "[bot] hi -- bot")
+	}
+
+	embed := &discordgo.MessageSend{Content: "hi", Embeds: []*discordgo.MessageEmbed{{Title: "t"}}}
+	if got := responseChunks(config, embed); len(got) != 1 || got[0] != "hi" {
+		t.Errorf("embed response = %q, want %q", got, "hi")
+	}
+}
+
+func TestResponseChunksOverLimit(t *testing.T) {
+	config := &Config{ResponsePrefix: "[bot] ", ResponseSuffix: "\n-- sent by bot"}
+	tests := []struct {
+		name string
+		body string
+		// sep is what the body was split on.
+		sep string
+	}{
+		{name: "fits without signature", body: strings.Repeat("abcd ", 399) + "abcd", sep: " "},
+		{name: "no spaces", body: strings.Repeat("x", 3000)},
+		{name: "words", body: strings.TrimSpace(strings.Repeat("word ", 1000)), sep: " "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(config.ResponsePrefix)+len(tt.body)+len(config.ResponseSuffix) <= maxMessageLength {
+				t.Fatalf("body of %d bytes fits with the signature", len(tt.body))
+			}
+			chunks := responseChunks(config, &discordgo.MessageSend{Content: tt.body})
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want the body split", len(chunks))
+			}
+			for i, chunk := range chunks {
+				if len(chunk) > maxMessageLength {
+					t.Errorf("chunk %d is %d bytes, want at most %d", i, len(chunk), maxMessageLength)
+				}
+			}
+			last := chunks[len(chunks)-1]
+			if !strings.HasPrefix(chunks[0], config.ResponsePrefix) || !strings.HasSuffix(last, config.ResponseSuffix) {
+				t.Fatal("chunks don't start with the prefix and end with the whole suffix")
+			}
+			chunks[0] = strings.TrimPrefix(chunks[0], config.ResponsePrefix)
+			chunks[len(chunks)-1] = strings.TrimSuffix(last, config.ResponseSuffix)
+			if got := strings.Join(chunks, tt.sep); got != tt.body {
+				t.Error("chunks without the signature don't give back the whole body")
+			}
+		})
+	}
+}
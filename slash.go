@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxSlashCommands is the most global slash commands Discord allows.
+const maxSlashCommands = 100
+
+// slashNamePattern matches the characters Discord allows in slash command
+// names. Names must also be lowercase.
+var slashNamePattern = regexp.MustCompile(`^[-_\p{L}\p{N}]{1,32}$`)
+
+// validSlashName reports whether name may be used as a slash command name.
+func validSlashName(name string) bool {
+	return slashNamePattern.MatchString(name) && strings.ToLower(name) == name
+}
+
+// registerSlashCommands registers every configured command as a slash
+// command, replacing all previously registered commands so that commands
+// removed from the config are removed from Discord too.
+func registerSlashCommands(s *discordgo.Session, config *Config) error {
+	commands := slashCommands(config)
+	_, err := s.ApplicationCommandBulkOverwrite(s.State.User.ID, "", commands)
+	if err != nil {
+		return err
+	}
+	slog.Info("slash commands registered", "count", len(commands))
+	return nil
+}
+
+// slashCommands returns the slash commands for the configured commands.
+// Commands with names Discord doesn't allow are skipped.
+func slashCommands(config *Config) []*discordgo.ApplicationCommand {
+	// Sort names so the same commands are registered on every start.
+	names := make([]string, 0, len(config.lookup))
+	for name := range config.lookup {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var commands []*discordgo.ApplicationCommand
+	for _, name := range names {
+		if !validSlashName(name) {
+			slog.Warn("command name is not a valid slash command name; skipping it", "command", name)
+			continue
+		}
+		if !config.lookup[name].hasResponse() {
+			// Slash commands can't be reacted to, so there is nothing to do.
+			continue
+		}
+		if len(commands) == maxSlashCommands {
+			slog.Warn("too many slash commands; skipping it", "command", name)
+			continue
+		}
+		commands = append(commands, &discordgo.ApplicationCommand{
+			Name:        name,
+			Description: "Responds to " + config.Prefix.primary() + config.lookup[name].Name,
+		})
+	}
+	return commands
+}
+
+// slashCommandsMissing reports whether any of the wanted slash commands
+// aren't among those registered.
+func slashCommandsMissing(registered, want []*discordgo.ApplicationCommand) bool {
+	have := make(map[string]bool, len(registered))
+	for _, cmd := range registered {
+		have[cmd.Name] = true
+	}
+	for _, cmd := range want {
+		if !have[cmd.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+func interactionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	config := CurrentConfig.Load()
+
+	// Handle clicks of buttons sent with responses.
+	if i.Type == discordgo.InteractionMessageComponent {
+		handleButton(s, i, config)
+		return
+	}
+
+	// Only handle slash commands, if enabled.
+	if !config.SlashCommands || i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	// Ignore all DMs, unless they are allowed.
+	if i.GuildID == "" && !config.AllowDM {
+		return
+	}
+
+	// The user is given in the member info in guilds, and directly in DMs.
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	// Ignore blacklisted and unapproved users.
+	if isBlacklisted(config, user.ID) {
+		slog.Debug("ignoring interaction from blacklisted user", "author_id", user.ID)
+		return
+	}
+	if !isApproved(s, config, i.GuildID, user, i.Member) {
+		return
+	}
+	if !meetsAgeRequirements(s, config, i.GuildID, user, i.Member, time.Now()) {
+		return
+	}
+
+	// Check if the interaction is for a command.
+	cmd, isCmd := config.lookup[i.ApplicationCommandData().Name]
+	if !isCmd || !cmd.hasResponse() {
+		return
+	}
+
+	// If the command may not be used in this channel, or the bot may not
+	// send in it, do nothing.
+	if !cmd.allowedIn(i.ChannelID) {
+		return
+	}
+	if !canSendIn(config, i.ChannelID) {
+		slog.Info("channel is not in allowed_channels; skipping response", "command", cmd.Name, "channel_id", i.ChannelID)
+		return
+	}
+
+	// If the command is restricted to other users or roles, do nothing.
+	if !cmd.allowedFor(s, i.GuildID, user, i.Member) {
+		return
+	}
+
+	// If the user lacks the command's required permission, do nothing.
+	if !cmd.permittedFor(s, i.GuildID, user.ID, i.ChannelID) {
+		return
+	}
+
+	// If the command is on cooldown within its scope, do nothing.
+	if !commandReady(config, cmd, user.ID, i.ChannelID) {
+		return
+	}
+
+	// If the user is on cooldown, tell them so privately if enabled.
+	data := TemplateData{
+		User:      user.Username,
+		Mention:   user.Mention(),
+		ChannelID: i.ChannelID,
+		GuildID:   i.GuildID,
+		Locale:    string(i.Locale),
+	}
+	if remaining, ok := checkCooldown(config, user.ID, memberRoles(i.Member), cmd.Name); !ok {
+		text, ok := cooldownNotice(config, user.ID, cmd.Name, remaining, data)
+		if !ok {
+			return
+		}
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: text,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			slog.Error("error sending cooldown message", "command", cmd.Name, "err", err)
+		}
+		return
+	}
+
+	// If the user has used up their quota, do nothing. Quota is only
+	// checked once the user is off cooldown, so that uses blocked by the
+	// cooldown don't count against it.
+	if !checkQuota(s, config, i.GuildID, user.ID) {
+		slog.Debug("ignoring interaction from user over quota", "author_id", user.ID, "guild_id", i.GuildID)
+		return
+	}
+
+	// Start the command's cooldown within its scope.
+	if !startCommandCooldown(config, cmd, user.ID, i.ChannelID) {
+		return
+	}
+
+	// Render the command's responses.
+	vals, err := cmd.respond(data)
+	if err != nil {
+		slog.Error("error rendering response", "command", cmd.Name, "err", err)
+		return
+	}
+
+	// Transform the responses, if enabled.
+	for _, val := range vals {
+		applyPipeline(context.Background(), config.pipeline, val)
+	}
+
+	// Respond to the interaction.
+	err = respondInteraction(s, i.Interaction, vals[0], cmd.responseFlags())
+	if err != nil {
+		slog.Error("error sending response", "command", cmd.Name, "err", err)
+		return
+	}
+
+	// Send the rest of a sequence as follow-ups in the background, so that
+	// its delays don't hold up other interactions.
+	if len(vals) > 1 {
+		go func() {
+			for _, val := range vals[1:] {
+				time.Sleep(cmd.SequenceDelay)
+				err := sendFollowup(s, i.Interaction, val, cmd.responseFlags())
+				if err != nil {
+					slog.Error("error sending response", "command", cmd.Name, "err", err)
+					return
+				}
+			}
+		}()
+	}
+	Stats.record(cmd.Name)
+	cmd.logHandled(user.ID, i.ChannelID)
+	audit(s, config, user, cmd.Name, i.ChannelID)
+	emitEvent(config, user, cmd.Name, i.GuildID, i.ChannelID)
+}
+
+// responseFlags returns the flags to respond to the command's interactions
+// with.
+func (c *Command) responseFlags() discordgo.MessageFlags {
+	if c.Ephemeral {
+		return discordgo.MessageFlagsEphemeral
+	}
+	return 0
+}
+
+// respondInteraction responds to the interaction with the given flags. Text
+// responses that are too long are split, with the remaining chunks sent as
+// follow-ups.
+func respondInteraction(s *discordgo.Session, i *discordgo.Interaction, response *discordgo.MessageSend, flags discordgo.MessageFlags) error {
+	chunks := responseChunks(CurrentConfig.Load(), response)
+
+	err := s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    chunks[0],
+			Embeds:     response.Embeds,
+			Files:      response.Files,
+			Components: response.Components,
+			Flags:      flags,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range chunks[1:] {
+		_, err = s.FollowupMessageCreate(i, true, &discordgo.WebhookParams{Content: chunk, Flags: flags})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendFollowup sends the response as a follow-up to the interaction with the
+// given flags. Text responses that are too long are split into multiple
+// follow-ups.
+func sendFollowup(s *discordgo.Session, i *discordgo.Interaction, response *discordgo.MessageSend, flags discordgo.MessageFlags) error {
+	chunks := responseChunks(CurrentConfig.Load(), response)
+
+	for j, chunk := range chunks {
+		params := &discordgo.WebhookParams{Content: chunk, Flags: flags}
+		if j == 0 {
+			params.Embeds = response.Embeds
+			params.Files = response.Files
+			params.Components = response.Components
+		}
+		_, err := s.FollowupMessageCreate(i, true, params)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestValidSlashName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "ping", want: true},
+		{name: "dice-roll", want: true},
+		{name: "dice_roll2", want: true},
+		{name: "café", want: true},
+		{name: ""},
+		{name: "Ping"},
+		{name: "two words"},
+		{name: "ping!"},
+		{name: "this-name-is-far-too-long-for-discord"},
+	}
+	for _, tt := range tests {
+		if got := validSlashName(tt.name); got != tt.want {
+			t.Errorf("validSlashName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSlashCommands(t *testing.T) {
+	config := &Config{Prefix: Prefixes{"!"}, Commands: map[string]CommandConfig{
+		"ping":      {Output: Responses{{Text: "pong"}}},
+		"Bad Name":  {Output: Responses{{Text: "skipped"}}},
+		"thumbs-up": {Reaction: "👍"},
+	}}
+	buildLookup(config)
+
+	commands := slashCommands(config)
+	if len(commands) != 1 || commands[0].Name != "ping" || commands[0].Description != "Responds to !ping" {
+		t.Errorf("slashCommands = %+v, want only ping", commands)
+	}
+}
+
+func TestResponseFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		ephemeral bool
+		want      discordgo.MessageFlags
+	}{
+		{name: "default", want: 0},
+		{name: "ephemeral", ephemeral: true, want: discordgo.MessageFlagsEphemeral},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := newCommand("ping", CommandConfig{Output: Responses{{Text: "pong"}}, Ephemeral: tt.ephemeral}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cmd.responseFlags(); got != tt.want {
+				t.Errorf("responseFlags = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
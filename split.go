@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxMessageLength is the longest message Discord accepts.
+	maxMessageLength = 2000
+	// codeFence opens and closes a code block.
+	codeFence = "```"
+)
+
+// splitMessage splits content into chunks no longer than limit, preferring to
+// split on newlines, then spaces. If a split falls inside a code block, the
+// block is closed at the end of the chunk and reopened in the next one.
+func splitMessage(content string, limit int) []string {
+	var chunks []string
+	var fence string // The fence that opened the current code block, if any.
+
+	for {
+		prefix := ""
+		if fence != "" {
+			prefix = fence + "\n"
+		}
+
+		// The rest of the content fits in a single chunk.
+		if len(prefix)+len(content) <= limit {
+			return append(chunks, prefix+content)
+		}
+
+		// Leave room to reopen and close a code block.
+		room := limit - len(prefix) - len("\n"+codeFence)
+		cut, skip := splitPoint(content, room)
+		chunk := content[:cut]
+		content = content[cut+skip:]
+
+		// Close the code block if the chunk ends inside one.
+		fence = openFence(fence, chunk)
+		if fence != "" {
+			chunk += "\n" + codeFence
+		}
+		chunks = append(chunks, prefix+chunk)
+	}
+}
+
+// splitPoint returns where to split s so the first part is no longer than
+// room, and how many separator bytes to drop after the split.
+func splitPoint(s string, room int) (int, int) {
+	if room < 1 {
+		room = 1
+	}
+	if i := strings.LastIndexByte(s[:room], '\n'); i > 0 {
+		return i, 1
+	}
+	if i := strings.LastIndexByte(s[:room], ' '); i > 0 {
+		return i, 1
+	}
+
+	// No good place to split; avoid splitting in the middle of a rune.
+	cut := room
+	for cut > 1 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return cut, 0
+}
+
+// openFence returns the fence of the code block that is open at the end of
+// chunk, given the fence that was open at its start, or "" if none is open.
+func openFence(fence, chunk string) string {
+	for {
+		i := strings.Index(chunk, codeFence)
+		if i < 0 {
+			return fence
+		}
+		chunk = chunk[i+len(codeFence):]
+
+		if fence != "" {
+			// This fence closes the open block.
+			fence = ""
+			continue
+		}
+
+		// This fence opens a block, possibly with a language tag.
+		fence = codeFence
+		end := strings.IndexByte(chunk, '\n')
+		if end < 0 {
+			end = len(chunk)
+		}
+		if lang := chunk[:end]; lang != "" && !strings.ContainsAny(lang, " `") {
+			fence += lang
+		}
+	}
+}
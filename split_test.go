@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// sep is the separator every split should land on, if any.
+		sep        string
+		wantChunks int
+	}{
+		{name: "short", content: "pong", wantChunks: 1},
+		{name: "exactly the limit", content: strings.Repeat("a", maxMessageLength), wantChunks: 1},
+		{name: "words", content: strings.Repeat("word ", 1000), sep: " ", wantChunks: 3},
+		{name: "lines", content: strings.Repeat("a line with some words\n", 200), sep: "\n", wantChunks: 3},
+		{name: "no spaces", content: strings.Repeat("é", 1500), sep: "", wantChunks: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitMessage(tt.content, maxMessageLength)
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.wantChunks)
+			}
+			for i, chunk := range chunks {
+				if len(chunk) > maxMessageLength {
+					t.Errorf("chunk %d is %d bytes, want at most %d", i, len(chunk), maxMessageLength)
+				}
+				if !utf8.ValidString(chunk) {
+					t.Errorf("chunk %d splits a rune", i)
+				}
+			}
+			if got := strings.Join(chunks, tt.sep); got != tt.content {
+				t.Errorf("chunks joined with %q don't give back the content", tt.sep)
+			}
+		})
+	}
+}
+
+func TestSplitMessageOnWordBoundaries(t *testing.T) {
+	chunks := splitMessage(strings.Repeat("word ", 1000), maxMessageLength)
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if !strings.HasSuffix(chunk, "word") || !strings.HasPrefix(chunks[i+1], "word") {
+			t.Errorf("split after chunk %d lands mid-word: %q | %q", i, chunk[len(chunk)-10:], chunks[i+1][:10])
+		}
+	}
+}
+
+func TestSplitMessageCodeBlock(t *testing.T) {
+	body := strings.Repeat("fmt.Println(\"hello\")\n", 150)
+	content := "Here you go:\n```go\n" + body + "```\nDone."
+	chunks := splitMessage(content, maxMessageLength)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if !strings.HasSuffix(chunks[0], "\n```") {
+		t.Errorf("first chunk ends with %q, want the code block closed", chunks[0][len(chunks[0])-10:])
+	}
+	if !strings.HasPrefix(chunks[1], "```go\n") {
+		t.Errorf("second chunk starts with %q, want the code block reopened with its language", chunks[1][:10])
+	}
+	if !strings.HasSuffix(chunks[1], "```\nDone.") {
+		t.Errorf("second chunk ends with %q, want the original closing fence", chunks[1][len(chunks[1])-10:])
+	}
+	for i, chunk := range chunks {
+		if n := strings.Count(chunk, codeFence); n%2 != 0 {
+			t.Errorf("chunk %d has %d fences, want them balanced", i, n)
+		}
+	}
+}
+
+func TestOpenFence(t *testing.T) {
+	tests := []struct {
+		fence string
+		chunk string
+		want  string
+	}{
+		{chunk: "no code", want: ""},
+		{chunk: "```\ncode", want: "```"},
+		{chunk: "```go\ncode", want: "```go"},
+		{chunk: "```go\ncode\n```\ntext", want: ""},
+		{chunk: "inline ```code``` here", want: ""},
+		{fence: "```go", chunk: "more code", want: "```go"},
+		{fence: "```go", chunk: "code\n```\ntext", want: ""},
+		{fence: "```go", chunk: "code\n```\n```py\nmore", want: "```py"},
+	}
+	for _, tt := range tests {
+		if got := openFence(tt.fence, tt.chunk); got != tt.want {
+			t.Errorf("openFence(%q, %q) = %q, want %q", tt.fence, tt.chunk, got, tt.want)
+		}
+	}
+}
+
+func TestHandleLongResponse(t *testing.T) {
+	long := strings.TrimSpace(strings.Repeat("word ", 1000))
+	sent := handleMessages(t, "prefix: \"!\"\ncommands:\n  long: "+long+"\n", testMessage("30", "!long", false))
+	if len(sent) != 3 {
+		t.Fatalf("sent %d messages, want 3", len(sent))
+	}
+	for i, text := range sent {
+		if len(text) > maxMessageLength {
+			t.Errorf("message %d is %d bytes, want at most %d", i, len(text), maxMessageLength)
+		}
+	}
+	if got := strings.Join(sent, " "); got != long {
+		t.Error("messages joined don't give back the response")
+	}
+}
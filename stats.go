@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultStatsTop is how many commands are listed by the stats command if
+// no number is set.
+const defaultStatsTop = 10
+
+// commandStats counts how many times each command has been used.
+type commandStats struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// newCommandStats returns an empty commandStats.
+func newCommandStats() *commandStats {
+	return &commandStats{counts: make(map[string]int)}
+}
+
+// record counts a use of the command.
+func (c *commandStats) record(command string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[command]++
+}
+
+// reset clears all counts.
+func (c *commandStats) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts = make(map[string]int)
+}
+
+// commandCount is the number of times a command has been used.
+type commandCount struct {
+	Command string
+	Count   int
+}
+
+// top returns the n most used commands, most used first. Commands with the
+// same count are sorted by name.
+func (c *commandStats) top(n int) []commandCount {
+	c.mu.Lock()
+	counts := make([]commandCount, 0, len(c.counts))
+	for command, count := range c.counts {
+		counts = append(counts, commandCount{Command: command, Count: count})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Command < counts[j].Command
+	})
+
+	if n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// statsText returns a list of the most used commands, used with the given
+// prefix.
+func statsText(config *Config, prefix string) string {
+	n := config.StatsTop
+	if n <= 0 {
+		n = defaultStatsTop
+	}
+
+	counts := Stats.top(n)
+	if len(counts) == 0 {
+		return "No commands have been used yet."
+	}
+
+	var b strings.Builder
+	b.WriteString("Most used commands:")
+	for i, c := range counts {
+		fmt.Fprintf(&b, "\n%d. `%s%s`: %d", i+1, prefix, c.Command, c.Count)
+	}
+	return b.String()
+}
+
+// resetStats periodically resets the command stats, if a reset interval is
+// set, until ctx is done.
+func resetStats(ctx context.Context) {
+	for {
+		interval := CurrentConfig.Load().statsResetInterval()
+		if interval <= 0 {
+			// Check again later in case the config changes.
+			interval = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			if CurrentConfig.Load().statsResetInterval() > 0 {
+				Stats.reset()
+			}
+		}
+	}
+}
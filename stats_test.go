@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestStatsCommand(t *testing.T) {
+	Stats = newCommandStats()
+	const config = "prefix: \"!\"\nstats_command: stats\nstats_top: 2\ncommands:\n  ping: pong\n  roll: \"4\"\n  hi: hello\n"
+	sent := handleMessages(t, config,
+		testMessage("30", "!ping", false),
+		testMessage("31", "!roll", false),
+		testMessage("32", "!ping", false),
+		testMessage("33", "!hi", false),
+		testMessage("34", "!roll", false),
+		testMessage("35", "!ping", false),
+		testMessage("30", "!stats", false),
+	)
+
+	want := "Most used commands:\n1. `!ping`: 3\n2. `!roll`: 2"
+	if len(sent) != 7 || sent[6] != want {
+		t.Errorf("sent %q, want the stats %q last", sent, want)
+	}
+}
+
+func TestCommandStatsTop(t *testing.T) {
+	stats := newCommandStats()
+	for _, command := range []string{"b", "a", "c", "a", "b", "a"} {
+		stats.record(command)
+	}
+	got := stats.top(5)
+	want := []commandCount{{"a", 3}, {"b", 2}, {"c", 1}}
+	if len(got) != len(want) {
+		t.Fatalf("top = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("top[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	stats.reset()
+	if got := stats.top(5); len(got) != 0 {
+		t.Errorf("top after reset = %v, want none", got)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// formatUptime formats d as days, hours, minutes, and seconds, omitting
+// leading zero units.
+func formatUptime(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if days > 0 || hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if days > 0 || hours > 0 || minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	parts = append(parts, fmt.Sprintf("%ds", seconds))
+	return strings.Join(parts, " ")
+}
+
+// statusText returns the bot's status given its uptime, number of loaded
+// commands, and number of config reloads.
+func statusText(version string, uptime time.Duration, commands int, reloads int64) string {
+	return fmt.Sprintf("Version: %s\nUptime: %s\nCommands: %d\nConfig reloads: %d",
+		version, formatUptime(uptime), commands, reloads)
+}
+
+// commandCount returns the number of commands loaded from the config.
+func (c *Config) commandCount() int {
+	names := make(map[string]bool, len(c.lookup))
+	for _, cmd := range c.lookup {
+		names[cmd.Name] = true
+	}
+	return len(names) + len(c.contains) + len(c.patterns)
+}
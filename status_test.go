@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0s"},
+		{d: 1500 * time.Millisecond, want: "1s"},
+		{d: 59 * time.Second, want: "59s"},
+		{d: time.Minute, want: "1m 0s"},
+		{d: time.Hour + 5*time.Second, want: "1h 0m 5s"},
+		{d: 2*24*time.Hour + 3*time.Minute, want: "2d 0h 3m 0s"},
+		{d: 400*24*time.Hour + 23*time.Hour + 59*time.Minute + 59*time.Second, want: "400d 23h 59m 59s"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.d); got != tt.want {
+			t.Errorf("formatUptime(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	got := statusText("v1.2.3", 90*time.Minute, 12, 3)
+	want := "Version: v1.2.3\nUptime: 1h 30m 0s\nCommands: 12\nConfig reloads: 3"
+	if got != want {
+		t.Errorf("statusText = %q, want %q", got, want)
+	}
+}
+
+func TestCommandCount(t *testing.T) {
+	config := useConfig(t, "commands:\n  ping:\n    output: pong\n    aliases: [p]\n  coffee:\n    match: contains\n    output: Coffee!\n  \"^hi+$\":\n    match: regex\n    output: Hello\n")
+	if got := config.commandCount(); got != 3 {
+		t.Errorf("commandCount = %d, want 3", got)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql"
+
+	_ "modernc.org/sqlite"
+)
+
+// openStorage opens the SQLite database at path, creating the commands table
+// if it doesn't exist.
+func openStorage(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only supports one writer at a time.
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS commands (name TEXT PRIMARY KEY, output TEXT)`)
+	if err != nil {
+		// The table error is more useful than any error closing.
+		_ = db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// loadCommands reads all commands from the database.
+func loadCommands(db *sql.DB) (map[string]CommandConfig, error) {
+	rows, err := db.Query(`SELECT name, output FROM commands`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := make(map[string]CommandConfig)
+	for rows.Next() {
+		var name, output string
+		err = rows.Scan(&name, &output)
+		if err != nil {
+			return nil, err
+		}
+		commands[name] = CommandConfig{Output: Responses{{Text: output}}}
+	}
+
+	return commands, rows.Err()
+}
+
+// saveCommand adds or replaces a command in the database.
+func saveCommand(db *sql.DB, name, output string) error {
+	_, err := db.Exec(`INSERT INTO commands (name, output) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET output = excluded.output`, name, output)
+	return err
+}
+
+// removeCommand deletes a command from the database.
+func removeCommand(db *sql.DB, name string) error {
+	_, err := db.Exec(`DELETE FROM commands WHERE name = ?`, name)
+	return err
+}
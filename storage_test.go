@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStorageCommands(t *testing.T) {
+	db, err := openStorage(":memory:")
+	if err != nil {
+		t.Fatalf("openStorage: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO commands (name, output) VALUES ('ping', 'pong'), ('hi', 'Hello {{.User}}')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saveCommand(db, "ping", "pong!"); err != nil {
+		t.Fatalf("saveCommand: %v", err)
+	}
+	if err := saveCommand(db, "bye", "Goodbye"); err != nil {
+		t.Fatalf("saveCommand: %v", err)
+	}
+	if err := removeCommand(db, "hi"); err != nil {
+		t.Fatalf("removeCommand: %v", err)
+	}
+
+	got, err := loadCommands(db)
+	if err != nil {
+		t.Fatalf("loadCommands: %v", err)
+	}
+	want := map[string]CommandConfig{
+		"ping": {Output: Responses{{Text: "pong!"}}},
+		"bye":  {Output: Responses{{Text: "Goodbye"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadCommands = %v, want %v", got, want)
+	}
+}
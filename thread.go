@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxThreadNameLength is the longest thread name Discord allows.
+const maxThreadNameLength = 100
+
+// supportsThreads reports whether threads can be started on messages in
+// channels of the given type.
+func supportsThreads(channelType discordgo.ChannelType) bool {
+	return channelType == discordgo.ChannelTypeGuildText || channelType == discordgo.ChannelTypeGuildNews
+}
+
+// threadStart returns the payload to start a thread for the command on m.
+// The thread is named after the message, or the command if the message has
+// no text.
+func threadStart(m *discordgo.MessageCreate, command string) *discordgo.ThreadStart {
+	name := strings.TrimSpace(m.Content)
+	if name == "" {
+		name = command
+	}
+	if runes := []rune(name); len(runes) > maxThreadNameLength {
+		name = string(runes[:maxThreadNameLength])
+	}
+	return &discordgo.ThreadStart{Name: name}
+}
+
+// startThread starts a thread for the command on m and returns its ID. If
+// threads can't be started in the channel, or starting one fails, it returns
+// "" so that the response is sent to the channel instead.
+func startThread(s Messenger, m *discordgo.MessageCreate, config *Config, command string) string {
+	if m.GuildID == "" {
+		return ""
+	}
+
+	ctx, cancel := requestContext(config)
+	defer cancel()
+
+	channel, err := s.Channel(m.ChannelID, discordgo.WithContext(ctx))
+	if err != nil {
+		slog.Warn("error getting channel; responding without a thread", "command", command, "channel_id", m.ChannelID, "err", err)
+		return ""
+	}
+	if !supportsThreads(channel.Type) {
+		slog.Debug("channel does not support threads; responding without a thread", "command", command, "channel_id", m.ChannelID)
+		return ""
+	}
+
+	thread, err := s.MessageThreadStartComplex(m.ChannelID, m.ID, threadStart(m, command), discordgo.WithContext(ctx))
+	if err != nil {
+		slog.Warn("error starting thread; responding without a thread", "command", command, "channel_id", m.ChannelID, "err", err)
+		return ""
+	}
+	return thread.ID
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSupportsThreads(t *testing.T) {
+	tests := []struct {
+		channelType discordgo.ChannelType
+		want        bool
+	}{
+		{channelType: discordgo.ChannelTypeGuildText, want: true},
+		{channelType: discordgo.ChannelTypeGuildNews, want: true},
+		{channelType: discordgo.ChannelTypeDM},
+		{channelType: discordgo.ChannelTypeGuildVoice},
+		{channelType: discordgo.ChannelTypeGuildPublicThread},
+		{channelType: discordgo.ChannelTypeGuildForum},
+	}
+	for _, tt := range tests {
+		if got := supportsThreads(tt.channelType); got != tt.want {
+			t.Errorf("supportsThreads(%d) = %t, want %t", tt.channelType, got, tt.want)
+		}
+	}
+}
+
+func TestThreadStart(t *testing.T) {
+	long := strings.Repeat("é", maxThreadNameLength+10)
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "message", content: "  !help me please ", want: "!help me please"},
+		{name: "no text", content: "", want: "help"},
+		{name: "too long", content: long, want: strings.Repeat("é", maxThreadNameLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testMessage("30", tt.content, false)
+			if got := threadStart(m, "help"); got.Name != tt.want {
+				t.Errorf("thread name = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleThread(t *testing.T) {
+	const config = "prefix: \"!\"\nallow_dm: true\ncommands:\n  help:\n    output: Let's talk here\n    thread: true\n"
+	tests := []struct {
+		name string
+		msg  *discordgo.MessageCreate
+		want []sentMessage
+	}{
+		{name: "guild", msg: testMessage("30", "!help", false), want: []sentMessage{{ChannelID: "thread-200", Content: "Let's talk here"}}},
+		{name: "DM", msg: inDM(testMessage("30", "!help", false)), want: []sentMessage{{ChannelID: "20", Content: "Let's talk here"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMessenger{}
+			handleWith(t, fake, config, tt.msg)
+			if got := fake.messages(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// readTokenFile returns the token in the file at path, ignoring surrounding
+// whitespace.
+func readTokenFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.New("token file is empty")
+	}
+	return token, nil
+}
+
+// parseTokens splits a comma-separated list of tokens, ignoring surrounding
+// whitespace and blank entries.
+func parseTokens(s string) []string {
+	var tokens []string
+	for _, token := range strings.Split(s, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// openWithFailover calls open with each token in order until it succeeds,
+// and returns the index of the token that worked. It returns the last error
+// if every token fails. Tokens are never logged, only their indexes.
+func openWithFailover(tokens []string, open func(token string) error) (int, error) {
+	err := errors.New("no tokens")
+	for i, token := range tokens {
+		err = open(token)
+		if err == nil {
+			return i, nil
+		}
+		if i+1 < len(tokens) {
+			slog.Warn("error connecting with token; trying the next one", "token_index", i, "err", err)
+		}
+	}
+	return 0, err
+}
+
+// tokenChanged reports whether next is a new token to replace current.
+func tokenChanged(current, next string) bool {
+	return next != "" && next != current
+}
+
+// setToken sets the token the session authenticates with.
+func setToken(dg *discordgo.Session, token string) {
+	dg.Token = "Bot " + token
+	dg.Identify.Token = dg.Token
+}
+
+// reloadToken reads the token from the file at path and, if it has changed,
+// reconnects the session with it. The new token is checked before the
+// session is closed, so an invalid token leaves the session as it is. If
+// reconnecting fails anyway, the session is reconnected with the old token.
+func reloadToken(dg *discordgo.Session, path string) {
+	next, err := readTokenFile(path)
+	if err != nil {
+		slog.Error("error reading token file", "err", err)
+		return
+	}
+	if !tokenChanged(Token, next) {
+		return
+	}
+
+	// Check the new token before giving up the current session.
+	check, err := discordgo.New("Bot " + next)
+	if err == nil {
+		_, err = check.User("@me")
+	}
+	if err != nil {
+		slog.Error("new token is invalid; keeping the current session", "err", err)
+		return
+	}
+
+	err = dg.Close()
+	if err != nil {
+		slog.Error("error closing discord session", "err", err)
+	}
+	setToken(dg, next)
+	err = dg.Open()
+	if err != nil {
+		slog.Error("error reconnecting with new token; reconnecting with the old one", "err", err)
+		setToken(dg, Token)
+		err = dg.Open()
+		if err != nil {
+			slog.Error("error reconnecting with old token", "err", err)
+		}
+		return
+	}
+	Token = next
+	slog.Info("reconnected with new token")
+
+	// Statuses are lost on reconnecting, so show them again.
+	config := CurrentConfig.Load()
+	PresenceRotator.start(config.Presence, config.presenceInterval())
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTokenChanged(t *testing.T) {
+	tests := []struct {
+		current, next string
+		want          bool
+	}{
+		{current: "old", next: "new", want: true},
+		{current: "", next: "new", want: true},
+		{current: "old", next: "old"},
+		{current: "old", next: ""},
+	}
+	for _, tt := range tests {
+		if got := tokenChanged(tt.current, tt.next); got != tt.want {
+			t.Errorf("tokenChanged(%q, %q) = %t, want %t", tt.current, tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		data    string
+		missing bool
+		want    string
+		wantErr bool
+	}{
+		{name: "token", data: "abc.def\n", want: "abc.def"},
+		{name: "blank", data: " \n", wantErr: true},
+		{name: "missing", missing: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if !tt.missing {
+				if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := readTokenFile(path)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("readTokenFile = %q, %v, want %q, error %t", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetToken(t *testing.T) {
+	dg, err := discordgo.New("Bot old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setToken(dg, "new")
+	if dg.Token != "Bot new" || dg.Identify.Token != "Bot new" {
+		t.Errorf("token = %q, identify token = %q, want %q", dg.Token, dg.Identify.Token, "Bot new")
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: ""},
+		{in: "primary", want: []string{"primary"}},
+		{in: " primary , backup ,, ", want: []string{"primary", "backup"}},
+	}
+	for _, tt := range tests {
+		if got := parseTokens(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTokens(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestOpenWithFailover(t *testing.T) {
+	errAuth := errors.New("authentication failed")
+	tests := []struct {
+		name      string
+		tokens    []string
+		accept    string
+		wantIndex int
+		wantTried []string
+		wantErr   bool
+	}{
+		{name: "first", tokens: []string{"primary", "backup"}, accept: "primary", wantIndex: 0, wantTried: []string{"primary"}},
+		{name: "failover", tokens: []string{"primary", "backup"}, accept: "backup", wantIndex: 1, wantTried: []string{"primary", "backup"}},
+		{name: "all fail", tokens: []string{"primary", "backup"}, wantTried: []string{"primary", "backup"}, wantErr: true},
+		{name: "no tokens", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t, "text", slog.LevelDebug)
+			var tried []string
+			index, err := openWithFailover(tt.tokens, func(token string) error {
+				tried = append(tried, token)
+				if token != tt.accept {
+					return errAuth
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr || index != tt.wantIndex {
+				t.Errorf("openWithFailover = %d, %v, want %d, error %t", index, err, tt.wantIndex, tt.wantErr)
+			}
+			if !reflect.DeepEqual(tried, tt.wantTried) {
+				t.Errorf("tried %q, want %q", tried, tt.wantTried)
+			}
+			for _, token := range tt.tokens {
+				if strings.Contains(logs.String(), token) {
+					t.Errorf("logs %q contain token %q", logs, token)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// typingDelayPerChar is how long it takes to "type" one character.
+	typingDelayPerChar = 20 * time.Millisecond
+	// defaultTypingMaxDelay is the longest typing delay if none is set.
+	defaultTypingMaxDelay = 3 * time.Second
+)
+
+// typingDelay returns how long to spend typing a response of the given
+// length, capped at max.
+func typingDelay(length int, max time.Duration) time.Duration {
+	if max <= 0 {
+		max = defaultTypingMaxDelay
+	}
+	if length <= 0 {
+		return 0
+	}
+
+	delay := time.Duration(length) * typingDelayPerChar
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// simulateTyping shows the typing indicator in the channel and waits as if
+// the response were being typed, for at most max.
+func simulateTyping(s Messenger, channelID string, response *discordgo.MessageSend, max time.Duration) {
+	ctx, cancel := requestContext(CurrentConfig.Load())
+	err := s.ChannelTyping(channelID, discordgo.WithContext(ctx))
+	cancel()
+	if err != nil {
+		slog.Error("error sending typing indicator", "err", err)
+		return
+	}
+	time.Sleep(typingDelay(responseLength(response), max))
+}
+
+// responseLength returns the number of characters to "type" for response.
+func responseLength(response *discordgo.MessageSend) int {
+	length := len(response.Content)
+	for _, embed := range response.Embeds {
+		length += len(embed.Title) + len(embed.Description)
+	}
+	return length
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypingDelay(t *testing.T) {
+	tests := []struct {
+		length int
+		max    time.Duration
+		want   time.Duration
+	}{
+		{length: 0, max: time.Second, want: 0},
+		{length: -1, max: time.Second, want: 0},
+		{length: 10, max: time.Second, want: 10 * typingDelayPerChar},
+		{length: 1000, max: time.Second, want: time.Second},
+		{length: 1000, max: 0, want: defaultTypingMaxDelay},
+		{length: 10, max: 0, want: 10 * typingDelayPerChar},
+	}
+	for _, tt := range tests {
+		if got := typingDelay(tt.length, tt.max); got != tt.want {
+			t.Errorf("typingDelay(%d, %v) = %v, want %v", tt.length, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validate returns all problems with the given config.
+func validate(config Config) []error {
+	var errs []error
+
+	// Sort keys so errors are reported in the same order on every load.
+	keys := make([]string, 0, len(config.Commands))
+	for k := range config.Commands {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	seen := make(map[string]string, len(keys))
+	for _, k := range keys {
+		before := len(errs)
+		if strings.TrimSpace(k) == "" {
+			errs = append(errs, fmt.Errorf("command %q: blank command name", k))
+		}
+
+		// Check for commands that are the same once normalized.
+		key := k
+		if config.CaseInsensitive {
+			key = strings.ToLower(k)
+		}
+		if other, exists := seen[key]; exists {
+			errs = append(errs, fmt.Errorf("command %q: duplicate of command %q", k, other))
+		} else {
+			seen[key] = k
+		}
+
+		// Check the match mode.
+		switch config.Commands[k].Match {
+		case "", matchExact, matchContains, matchRegex:
+		default:
+			errs = append(errs, fmt.Errorf("command %q: unknown match mode %q", k, config.Commands[k].Match))
+		}
+		switch config.Commands[k].CooldownScope {
+		case "", cooldownScopeGlobal, cooldownScopeUser, cooldownScopeChannel:
+		default:
+			errs = append(errs, fmt.Errorf("command %q: unknown cooldown_scope %q", k, config.Commands[k].CooldownScope))
+		}
+		switch config.Commands[k].Mode {
+		case "", modeRandom, modeSequence:
+		default:
+			errs = append(errs, fmt.Errorf("command %q: unknown mode %q", k, config.Commands[k].Mode))
+		}
+		if config.Commands[k].Args && (config.Commands[k].Regex || (config.Commands[k].Match != "" && config.Commands[k].Match != matchExact)) {
+			errs = append(errs, fmt.Errorf("command %q: args are only supported by exact matches", k))
+		}
+
+		// Check the webhook URL, if any.
+		if hook := config.Commands[k].Webhook; hook != "" {
+			if _, err := parseWebhookURL(hook); err != nil {
+				errs = append(errs, fmt.Errorf("command %q: %w", k, err))
+			}
+		}
+
+		// Check the target channel, if any.
+		if target := config.Commands[k].TargetChannel; target != "" {
+			if !isSnowflake(target) {
+				errs = append(errs, fmt.Errorf("command %q: target_channel %q: must be a channel ID", k, target))
+			}
+			if config.Commands[k].Webhook != "" {
+				errs = append(errs, fmt.Errorf("command %q: target_channel and webhook can't both be set", k))
+			}
+		} else if config.Commands[k].Acknowledge != "" {
+			errs = append(errs, fmt.Errorf("command %q: acknowledge requires target_channel", k))
+		}
+		if config.Commands[k].ConfirmTimeout < 0 {
+			errs = append(errs, fmt.Errorf("command %q: confirm_timeout %d: must not be negative", k, config.Commands[k].ConfirmTimeout))
+		}
+		if config.Commands[k].Thread && (config.Commands[k].TargetChannel != "" || config.Commands[k].Webhook != "") {
+			errs = append(errs, fmt.Errorf("command %q: thread can't be used with target_channel or webhook", k))
+		}
+
+		// Check for missing or blank responses.
+		output := config.Commands[k].Output
+		if len(output) == 0 && config.Commands[k].File == "" && config.Commands[k].Reaction == "" {
+			errs = append(errs, fmt.Errorf("command %q: no output, file, or reaction", k))
+		}
+		for i, r := range output {
+			if isBlank(r) {
+				errs = append(errs, fmt.Errorf("command %q: output %d is blank", k, i+1))
+			}
+			if r.Weight != nil && *r.Weight <= 0 {
+				errs = append(errs, fmt.Errorf("command %q: output %d: weight %d must be positive", k, i+1, *r.Weight))
+			}
+		}
+		if name := config.Commands[k].Permission; name != "" {
+			if _, ok := permissionNames[name]; !ok {
+				errs = append(errs, fmt.Errorf("command %q: unknown permission %q", k, name))
+			}
+		}
+
+		// Parse the command the way it is parsed when loaded, to catch
+		// problems such as malformed templates and regexes that would
+		// otherwise only cause it to be skipped. Commands that already have
+		// errors are skipped, so their problems aren't reported twice.
+		if len(errs) == before && config.Commands[k].enabled() {
+			cmd, err := newCommand(k, config.Commands[k], config.CaseInsensitive)
+			if err == nil {
+				err = cmd.addLocales(config.Locales)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("command %q: %w", k, err))
+			}
+		}
+	}
+
+	if config.SchemaVersion < 0 {
+		errs = append(errs, fmt.Errorf("version %d: must not be negative", config.SchemaVersion))
+	}
+
+	errs = append(errs, validateButtons(config.Commands)...)
+	errs = append(errs, validateLocales(config)...)
+	errs = append(errs, validateReactionRoles(config.ReactionRoles)...)
+
+	if config.Webhook != "" {
+		if _, err := parseWebhookURL(config.Webhook); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if config.EventWebhook != "" {
+		if err := validateEventWebhook(config.EventWebhook); err != nil {
+			errs = append(errs, fmt.Errorf("event_webhook: %w", err))
+		}
+	}
+
+	switch config.RateLimitMode {
+	case "", rateLimitDrop, rateLimitQueue:
+	default:
+		errs = append(errs, fmt.Errorf("rate_limit_mode %q: must be %q or %q", config.RateLimitMode, rateLimitDrop, rateLimitQueue))
+	}
+
+	switch config.SendQueuePolicy {
+	case "", queueBlock, queueDropNewest, queueDropOldest:
+	default:
+		errs = append(errs, fmt.Errorf("send_queue_policy %q: must be %q, %q, or %q", config.SendQueuePolicy, queueBlock, queueDropNewest, queueDropOldest))
+	}
+	if config.SendWorkers < 0 {
+		errs = append(errs, fmt.Errorf("send_workers %d: must not be negative", config.SendWorkers))
+	}
+
+	if config.MinAccountAgeDays < 0 {
+		errs = append(errs, fmt.Errorf("min_account_age_days %d: must not be negative", config.MinAccountAgeDays))
+	}
+	if config.MinMembershipDays < 0 {
+		errs = append(errs, fmt.Errorf("min_membership_days %d: must not be negative", config.MinMembershipDays))
+	}
+
+	// Sort role IDs so errors are reported in the same order on every load.
+	roles := make([]string, 0, len(config.RoleCooldowns))
+	for role := range config.RoleCooldowns {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		if !isSnowflake(role) {
+			errs = append(errs, fmt.Errorf("role_cooldowns: %q: must be a role ID", role))
+		}
+		if config.RoleCooldowns[role] < 0 {
+			errs = append(errs, fmt.Errorf("role_cooldowns: %q: cooldown %d must not be negative", role, config.RoleCooldowns[role]))
+		}
+	}
+
+	if config.UserQuota < 0 {
+		errs = append(errs, fmt.Errorf("user_quota %d: must not be negative", config.UserQuota))
+	}
+	if config.UserQuotaWindow < 0 {
+		errs = append(errs, fmt.Errorf("user_quota_window %d: must not be negative", config.UserQuotaWindow))
+	}
+
+	if config.QuotaTimeout < 0 {
+		errs = append(errs, fmt.Errorf("quota_timeout %d: must not be negative", config.QuotaTimeout))
+	}
+	if config.QuotaTimeout > maxTimeout {
+		errs = append(errs, fmt.Errorf("quota_timeout %d: must be at most %d seconds (28 days)", config.QuotaTimeout, maxTimeout))
+	}
+	if config.QuotaTimeoutStrikes < 0 {
+		errs = append(errs, fmt.Errorf("quota_timeout_strikes %d: must not be negative", config.QuotaTimeoutStrikes))
+	}
+	if config.QuotaTimeout > 0 && config.UserQuota == 0 {
+		errs = append(errs, errors.New("quota_timeout requires user_quota"))
+	}
+
+	if config.ConnectRetries < 0 {
+		errs = append(errs, fmt.Errorf("connect_retries %d: must not be negative", config.ConnectRetries))
+	}
+	if config.ConnectRetryDelay < 0 {
+		errs = append(errs, fmt.Errorf("connect_retry_delay %d: must not be negative", config.ConnectRetryDelay))
+	}
+
+	if len(config.ResponsePrefix)+len(config.ResponseSuffix) >= maxMessageLength {
+		errs = append(errs, fmt.Errorf("response_prefix and response_suffix: must be shorter than %d characters together", maxMessageLength))
+	}
+
+	for _, name := range config.ResponsePipeline {
+		if _, ok := middlewares[name]; !ok {
+			errs = append(errs, fmt.Errorf("response_pipeline: unknown middleware %q", name))
+		}
+	}
+
+	for i, p := range config.Presence {
+		if _, ok := presenceTypes[p.Type]; !ok && p.Type != "" {
+			errs = append(errs, fmt.Errorf("presence %d: unknown type %q", i+1, p.Type))
+		}
+		if strings.TrimSpace(p.Text) == "" {
+			errs = append(errs, fmt.Errorf("presence %d: blank text", i+1))
+		}
+	}
+
+	if err := validateShards(config.ShardID, config.ShardCount); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, id := range config.AllowedChannels {
+		if !isSnowflake(id) {
+			errs = append(errs, fmt.Errorf("allowed_channels: %q: must be a channel ID", id))
+		}
+	}
+
+	if config.OnlineChannel != "" && !isSnowflake(config.OnlineChannel) {
+		errs = append(errs, fmt.Errorf("online_channel %q: must be a channel ID", config.OnlineChannel))
+	}
+	if (config.OnlineChannel == "") != (config.OnlineMessage == "") {
+		errs = append(errs, errors.New("online_channel and online_message must be set together"))
+	}
+
+	if config.WhitelistEnabled && len(config.Whitelist) == 0 && len(config.WhitelistRoles) == 0 {
+		errs = append(errs, errors.New("whitelist enabled but no users or roles are whitelisted"))
+	}
+
+	return errs
+}
+
+// isSnowflake reports whether s is a valid Discord ID.
+func isSnowflake(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// isBlank reports whether r would produce an empty message.
+func isBlank(r Response) bool {
+	if r.Embed != nil {
+		return strings.TrimSpace(r.Embed.Title) == "" && strings.TrimSpace(r.Embed.Description) == ""
+	}
+	return strings.TrimSpace(r.Text) == ""
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		command CommandConfig
+		wantErr string
+	}{
+		{name: "valid", command: CommandConfig{Output: Responses{{Text: "pong"}}}},
+		{name: "malformed template", command: CommandConfig{Output: Responses{{Text: "{{.User"}}}, wantErr: "unclosed action"},
+		{name: "invalid regex", key: "(ping", command: CommandConfig{Output: Responses{{Text: "pong"}}, Match: matchRegex}, wantErr: "missing closing"},
+		{name: "invalid reaction", command: CommandConfig{Reaction: "<:bad>"}, wantErr: "emoji"},
+		{name: "short contains", key: "pi", command: CommandConfig{Output: Responses{{Text: "pong"}}, Match: matchContains}, wantErr: "too short"},
+		{name: "zero weight", command: CommandConfig{Output: Responses{{Text: "pong", Weight: new(int)}}}, wantErr: "must be positive"},
+		{name: "invalid target channel", command: CommandConfig{Output: Responses{{Text: "pong"}}, TargetChannel: "#mods"}, wantErr: "must be a channel ID"},
+		{name: "acknowledge without target", command: CommandConfig{Output: Responses{{Text: "pong"}}, Acknowledge: "Thanks"}, wantErr: "requires target_channel"},
+		{name: "unknown permission", command: CommandConfig{Output: Responses{{Text: "pong"}}, Permission: "fly"}, wantErr: "unknown permission"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.key
+			if key == "" {
+				key = "ping"
+			}
+			errs := validate(Config{Commands: map[string]CommandConfig{key: tt.command}})
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Fatalf("validate = %v, want no errors", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Fatalf("validate = %v, want one error containing %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pong := CommandConfig{Output: Responses{{Text: "pong"}}}
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "valid", config: Config{Commands: map[string]CommandConfig{"ping": pong}}},
+		{name: "blank command name", config: Config{Commands: map[string]CommandConfig{" ": pong}}, wantErr: "blank command name"},
+		{
+			name:    "blank output",
+			config:  Config{Commands: map[string]CommandConfig{"ping": {Output: Responses{{Text: " "}}}}},
+			wantErr: "output 1 is blank",
+		},
+		{
+			name:    "no output",
+			config:  Config{Commands: map[string]CommandConfig{"ping": {}}},
+			wantErr: "no output, file, or reaction",
+		},
+		{
+			name:    "duplicate after normalizing",
+			config:  Config{CaseInsensitive: true, Commands: map[string]CommandConfig{"Ping": pong, "ping": pong}},
+			wantErr: "duplicate of command",
+		},
+		{
+			name:   "same name in different case",
+			config: Config{Commands: map[string]CommandConfig{"Ping": pong, "ping": pong}},
+		},
+		{
+			name:    "empty whitelist",
+			config:  Config{WhitelistEnabled: true, Commands: map[string]CommandConfig{"ping": pong}},
+			wantErr: "whitelist",
+		},
+		{
+			name:   "whitelist of roles",
+			config: Config{WhitelistEnabled: true, WhitelistRoles: []string{"50"}, Commands: map[string]CommandConfig{"ping": pong}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validate(tt.config)
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Fatalf("validate = %v, want no errors", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Fatalf("validate = %v, want one error containing %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	config := Config{
+		WhitelistEnabled: true,
+		Commands: map[string]CommandConfig{
+			"":     {Output: Responses{{Text: "pong"}}},
+			"ping": {Output: Responses{{Text: ""}}},
+		},
+	}
+	if errs := validate(config); len(errs) != 3 {
+		t.Errorf("validate = %v, want 3 errors", errs)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long to wait after a change before reloading, so that
+// rapid writes result in a single reload.
+const watchDebounce = 500 * time.Millisecond
+
+// reloadOnSignal calls reload for every signal received on signals, until
+// ctx is done.
+func reloadOnSignal(ctx context.Context, signals <-chan os.Signal, reload func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			reload()
+		}
+	}
+}
+
+// watchConfig calls reload whenever the file at path changes. The returned
+// watcher must be closed to stop watching.
+func watchConfig(path string, reload func()) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// Watch the directory rather than the file itself, since many editors
+	// replace the file on save.
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		// The watch error is more useful than any error closing.
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					if timer != nil {
+						timer.Stop()
+					}
+					return
+				}
+				// Ignore changes to other files in the directory.
+				if filepath.Clean(event.Name) != filepath.Clean(path) {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				// Restart the debounce timer.
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDebounce, reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("error watching config", "err", err)
+			}
+		}
+	}()
+
+	return watcher, nil
+}
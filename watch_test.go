@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal)
+	reloads := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reloadOnSignal(ctx, signals, func() { reloads <- struct{}{} })
+	}()
+
+	signals <- syscall.SIGHUP
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("signal didn't trigger a reload")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reload loop didn't return after cancel")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("prefix: \"!\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var reloads atomic.Int32
+	reloaded := make(chan struct{}, 10)
+	watcher, err := watchConfig(path, func() {
+		reloads.Add(1)
+		reloaded <- struct{}{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to other files in the directory are ignored.
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "other.yaml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Rapid writes result in a single reload.
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(path, []byte("prefix: \"?\"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writes didn't trigger a reload")
+	}
+	time.Sleep(2 * watchDebounce)
+	if n := reloads.Load(); n != 1 {
+		t.Errorf("reloaded %d times, want 1", n)
+	}
+
+	// Closing the watcher stops reloads.
+	if err := watcher.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("prefix: \"!\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * watchDebounce)
+	if n := reloads.Load(); n != 1 {
+		t.Errorf("reloaded %d times after closing the watcher, want 1", n)
+	}
+}
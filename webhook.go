@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Webhook identifies a Discord webhook.
+type Webhook struct {
+	ID    string
+	Token string
+}
+
+// WebhookChannels caches the IDs of the channels webhooks post in, by
+// webhook ID. A webhook's channel is only looked up if allowed_channels is
+// set.
+var WebhookChannels sync.Map
+
+// webhookChannel returns the ID of the channel the webhook posts in, looking
+// it up the first time.
+func webhookChannel(s Messenger, hook *Webhook, options ...discordgo.RequestOption) (string, error) {
+	if channelID, ok := WebhookChannels.Load(hook.ID); ok {
+		return channelID.(string), nil
+	}
+	webhook, err := s.WebhookWithToken(hook.ID, hook.Token, options...)
+	if err != nil {
+		return "", err
+	}
+	WebhookChannels.Store(hook.ID, webhook.ChannelID)
+	return webhook.ChannelID, nil
+}
+
+// executeWebhook sends params through the webhook, unless the bot may not
+// send in the webhook's channel. Every message sent through a webhook goes
+// through executeWebhook, so that allowed_channels can't be bypassed.
+func executeWebhook(s Messenger, config *Config, hook *Webhook, params *discordgo.WebhookParams, options ...discordgo.RequestOption) error {
+	if len(config.AllowedChannels) > 0 {
+		channelID, err := webhookChannel(s, hook, options...)
+		if err != nil {
+			return fmt.Errorf("looking up webhook channel: %w", err)
+		}
+		if !canSendTo(s, config, channelID, options...) {
+			return errChannelNotAllowed
+		}
+	}
+	_, err := s.WebhookExecute(hook.ID, hook.Token, false, params, options...)
+	return err
+}
+
+// parseWebhookURL extracts the webhook ID and token from a webhook URL of
+// the form https://discord.com/api/webhooks/{id}/{token}.
+func parseWebhookURL(rawURL string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL %q: must be an https URL", rawURL)
+	}
+
+	// The path may include an API version, as in /api/v10/webhooks/...
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, part := range parts {
+		if part == "webhooks" && i+3 == len(parts) && parts[i+1] != "" && parts[i+2] != "" {
+			return &Webhook{ID: parts[i+1], Token: parts[i+2]}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid webhook URL %q: expected /api/webhooks/{id}/{token}", rawURL)
+}
+
+// sendWebhook sends a response through the webhook. Text responses are split
+// into multiple messages if they are too long.
+func sendWebhook(s Messenger, hook *Webhook, response *discordgo.MessageSend) error {
+	chunks := responseChunks(CurrentConfig.Load(), response)
+
+	for i, chunk := range chunks {
+		params := &discordgo.WebhookParams{Content: chunk}
+		if i == 0 {
+			params.Embeds = response.Embeds
+			params.Files = response.Files
+		}
+
+		err := sendWebhookParams(s, hook, params)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendWebhookParams sends a single message through the webhook.
+func sendWebhookParams(s Messenger, hook *Webhook, params *discordgo.WebhookParams) error {
+	config := CurrentConfig.Load()
+	ctx, cancel := requestContext(config)
+	defer cancel()
+
+	// Wait for the rate limiter.
+	err := waitToSend(ctx, Limiter, config.RateLimitMode)
+	if err != nil {
+		return err
+	}
+	return withRetry(ctx, func() error {
+		err := executeWebhook(s, config, hook, params, discordgo.WithContext(ctx))
+		if err != nil {
+			rewindFiles(params.Files)
+		}
+		return err
+	})
+}
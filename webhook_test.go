@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    *Webhook
+		wantErr bool
+	}{
+		{url: "https://discord.com/api/webhooks/123/abc-DEF_456", want: &Webhook{ID: "123", Token: "abc-DEF_456"}},
+		{url: "https://discord.com/api/v10/webhooks/123/abc", want: &Webhook{ID: "123", Token: "abc"}},
+		{url: "https://discordapp.com/api/webhooks/123/abc/", want: &Webhook{ID: "123", Token: "abc"}},
+		{url: "http://discord.com/api/webhooks/123/abc", wantErr: true},
+		{url: "https:///api/webhooks/123/abc", wantErr: true},
+		{url: "https://discord.com/api/webhooks/123", wantErr: true},
+		{url: "https://discord.com/api/webhooks/123/abc/extra", wantErr: true},
+		{url: "https://discord.com/api/channels/123/abc", wantErr: true},
+		{url: "not a url", wantErr: true},
+		{url: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseWebhookURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWebhookURL(%q) error = %v, want error %t", tt.url, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWebhookURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
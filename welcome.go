@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func guildMemberAdd(s *discordgo.Session, m *discordgo.GuildMemberAdd) {
+	config := CurrentConfig.Load()
+
+	// Do nothing unless welcome messages are enabled.
+	if config.WelcomeChannel == "" || config.welcomeMessage == nil || m.Member == nil || m.User == nil {
+		return
+	}
+
+	// Render the welcome message.
+	text, err := render(config.welcomeMessage, welcomeData(config, m.Member))
+	if err != nil {
+		slog.Error("error rendering welcome message", "err", err)
+		return
+	}
+
+	// Send the welcome message, split into multiple messages if it is too
+	// long.
+	for _, chunk := range splitMessage(text, maxMessageLength) {
+		err = waitToSend(context.Background(), Limiter, config.RateLimitMode)
+		if err == nil {
+			_, err = sendMessage(s, config, config.WelcomeChannel, &discordgo.MessageSend{Content: chunk})
+		}
+		if err != nil {
+			slog.Error("error sending welcome message", "err", err)
+			return
+		}
+	}
+	slog.Info("member welcomed", "user_id", m.User.ID, "guild_id", m.GuildID)
+}
+
+// welcomeData returns the template data for a welcome message to member.
+func welcomeData(config *Config, member *discordgo.Member) TemplateData {
+	return TemplateData{
+		User:      member.User.Username,
+		Mention:   member.User.Mention(),
+		ChannelID: config.WelcomeChannel,
+		GuildID:   member.GuildID,
+	}
+}
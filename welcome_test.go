@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestWelcomeMessage(t *testing.T) {
+	config := useConfig(t, "welcome_channel: \"30\"\nwelcome_message: \"Welcome, {{.Mention}}! Everyone say hi to {{.User}} in <#{{.ChannelID}}>.\"\n")
+	member := &discordgo.Member{GuildID: "10", User: &discordgo.User{ID: "42", Username: "newbie"}}
+
+	got, err := render(config.welcomeMessage, welcomeData(config, member))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Welcome, <@42>! Everyone say hi to newbie in <#30>."; got != want {
+		t.Errorf("welcome message = %q, want %q", got, want)
+	}
+}
+
+func TestWelcomeMessageUnset(t *testing.T) {
+	config := useConfig(t, "welcome_channel: \"30\"\n")
+	if config.welcomeMessage != nil {
+		t.Error("welcome message parsed when unset")
+	}
+}